@@ -0,0 +1,118 @@
+package address
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// HostPort is the address of a bmc, host is either an ip address or a hostname.
+type HostPort struct {
+	Host string
+	Port int
+}
+
+// Parse parses an address of a bmc, which can be an ipv4 address, a bracketed ipv6 address or a hostname,
+// each with or without port. Plain ipv6 addresses without brackets are accepted as well and can not carry a port,
+// plain ipv6 addresses which might end with a port are rejected because a port requires brackets.
+// If no port is given, the given default port is used.
+func Parse(address string, defaultPort int) (HostPort, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return HostPort{}, fmt.Errorf("address is empty")
+	}
+
+	if ap, err := netip.ParseAddrPort(address); err == nil {
+		return HostPort{Host: ap.Addr().String(), Port: int(ap.Port())}, nil
+	}
+
+	if strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]") {
+		ip, err := netip.ParseAddr(address[1 : len(address)-1])
+		if err != nil || !ip.Is6() {
+			return HostPort{}, fmt.Errorf("invalid address %q: brackets must enclose an ipv6 address", address)
+		}
+		return HostPort{Host: ip.String(), Port: defaultPort}, nil
+	}
+
+	if ip, err := netip.ParseAddr(address); err == nil {
+		if ip.Is6() && mightEndWithPort(address) {
+			return HostPort{}, fmt.Errorf("ambiguous address %q: an ipv6 address with a port must be enclosed in brackets, e.g. [fd00::10]:623", address)
+		}
+		return HostPort{Host: ip.String(), Port: defaultPort}, nil
+	}
+
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		if strings.ContainsAny(address, ":[]") {
+			return HostPort{}, fmt.Errorf("invalid address %q: %w", address, err)
+		}
+		return HostPort{Host: address, Port: defaultPort}, nil
+	}
+
+	if host == "" {
+		return HostPort{}, fmt.Errorf("invalid address %q: host is empty", address)
+	}
+
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return HostPort{}, fmt.Errorf("invalid port in address %q: %w", address, err)
+	}
+
+	return HostPort{Host: host, Port: int(port)}, nil
+}
+
+// mightEndWithPort returns true if the last group of the plain ipv6 address is a decimal number and the groups before
+// form a valid ipv6 address as well, e.g. fd00::10:623 is either the address fd00::10:623 or fd00::10 with port 623.
+func mightEndWithPort(address string) bool {
+	idx := strings.LastIndex(address, ":")
+	if idx < 0 || !strings.Contains(address[:idx], "::") {
+		return false
+	}
+	if _, err := strconv.ParseUint(address[idx+1:], 10, 16); err != nil {
+		return false
+	}
+	ip, err := netip.ParseAddr(address[:idx])
+	return err == nil && ip.Is6()
+}
+
+// FromAddrPort returns the HostPort of the given ip address and port.
+func FromAddrPort(ap netip.AddrPort) HostPort {
+	return HostPort{Host: ap.Addr().String(), Port: int(ap.Port())}
+}
+
+// AddrPort returns the address as netip.AddrPort, ok is false if the host is not an ip address.
+func (h HostPort) AddrPort() (netip.AddrPort, bool) {
+	ip, err := netip.ParseAddr(h.Host)
+	if err != nil {
+		return netip.AddrPort{}, false
+	}
+	return netip.AddrPortFrom(ip, uint16(h.Port)), true // nolint:gosec
+}
+
+// URLHost returns the host in a form which can be used in urls, ipv6 addresses get enclosed in brackets.
+func (h HostPort) URLHost() string {
+	if ip, err := netip.ParseAddr(h.Host); err == nil && ip.Is6() {
+		return "[" + ip.String() + "]"
+	}
+	return h.Host
+}
+
+// OutBandHost returns the host which is passed to connect.OutBand of go-hal. Go-hal builds the redfish url of the bmc from it
+// and passes the same host to ipmitool, ipv6 addresses must be enclosed in brackets for the former and must not for the latter.
+// Redfish is required to connect at all, therefore ipv6 addresses are enclosed in brackets and the ipmitool based commands
+// and the serial console do not work for bmcs with ipv6 addresses until go-hal separates both.
+func (h HostPort) OutBandHost() string {
+	return h.URLHost()
+}
+
+// IsIPv6 returns true if the host is an ipv6 address.
+func (h HostPort) IsIPv6() bool {
+	ip, err := netip.ParseAddr(h.Host)
+	return err == nil && ip.Is6()
+}
+
+func (h HostPort) String() string {
+	return net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+}
@@ -0,0 +1,110 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		address     string
+		want        HostPort
+		wantURLHost string
+		wantErr     error
+	}{
+		{
+			name:        "ipv4 with port",
+			address:     "10.0.0.1:6230",
+			want:        HostPort{Host: "10.0.0.1", Port: 6230},
+			wantURLHost: "10.0.0.1",
+		},
+		{
+			name:        "ipv4 without port",
+			address:     "10.0.0.1",
+			want:        HostPort{Host: "10.0.0.1", Port: 623},
+			wantURLHost: "10.0.0.1",
+		},
+		{
+			name:        "bracketed ipv6 with port",
+			address:     "[fd00::10]:6230",
+			want:        HostPort{Host: "fd00::10", Port: 6230},
+			wantURLHost: "[fd00::10]",
+		},
+		{
+			name:        "bracketed ipv6 without port",
+			address:     "[fd00::10]",
+			want:        HostPort{Host: "fd00::10", Port: 623},
+			wantURLHost: "[fd00::10]",
+		},
+		{
+			name:        "plain ipv6",
+			address:     "fd00::10",
+			want:        HostPort{Host: "fd00::10", Port: 623},
+			wantURLHost: "[fd00::10]",
+		},
+		{
+			name:        "hostname with port",
+			address:     "bmc.example.com:6230",
+			want:        HostPort{Host: "bmc.example.com", Port: 6230},
+			wantURLHost: "bmc.example.com",
+		},
+		{
+			name:        "hostname without port",
+			address:     "bmc.example.com",
+			want:        HostPort{Host: "bmc.example.com", Port: 623},
+			wantURLHost: "bmc.example.com",
+		},
+		{
+			name:        "plain ipv6 ending with a hex group",
+			address:     "fd00::1:ab",
+			want:        HostPort{Host: "fd00::1:ab", Port: 623},
+			wantURLHost: "[fd00::1:ab]",
+		},
+		{
+			name:    "plain ipv6 which might end with a port",
+			address: "fd00::10:623",
+			wantErr: fmt.Errorf(`ambiguous address "fd00::10:623": an ipv6 address with a port must be enclosed in brackets, e.g. [fd00::10]:623`),
+		},
+		{
+			name:    "bracketed ipv4",
+			address: "[10.0.0.1]",
+			wantErr: fmt.Errorf(`invalid address "[10.0.0.1]": brackets must enclose an ipv6 address`),
+		},
+		{
+			name:    "empty",
+			address: "",
+			wantErr: fmt.Errorf("address is empty"),
+		},
+		{
+			name:    "invalid port",
+			address: "bmc.example.com:foo",
+			wantErr: fmt.Errorf(`invalid port in address "bmc.example.com:foo": %w`, errors.New(`strconv.ParseUint: parsing "foo": invalid syntax`)),
+		},
+		{
+			name:    "missing host",
+			address: ":623",
+			wantErr: fmt.Errorf(`invalid address ":623": host is empty`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := Parse(tt.address, 623)
+			if diff := cmp.Diff(tt.wantErr, gotErr, testcommon.ErrorStringComparer()); diff != "" {
+				t.Errorf("error diff = %s", diff)
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+			if diff := cmp.Diff(tt.wantURLHost, got.URLHost()); diff != "" {
+				t.Errorf("url host diff = %s", diff)
+			}
+		})
+	}
+}
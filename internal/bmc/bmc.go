@@ -3,18 +3,25 @@ package bmc
 import (
 	"fmt"
 	"log/slog"
-	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/metal-stack/go-hal"
 	"github.com/metal-stack/go-hal/connect"
 	halslog "github.com/metal-stack/go-hal/pkg/logger/slog"
+	"github.com/metal-stack/metal-bmc/internal/address"
+	"github.com/metal-stack/metal-bmc/internal/credentials"
 	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/nsqio/go-nsq"
 )
 
 type BMCService struct {
-	log *slog.Logger
+	log      *slog.Logger
+	ipmiPort int
+	// usage coordinates password rotations with running commands
+	usage *credentials.Usage
+	// secrets contains the credentials of provisioned bmc users, nil if provisioning is disabled
+	secrets credentials.Backend
 	// NSQ related config options
 	mqAddress           string
 	mqCACertFile        string
@@ -23,11 +30,25 @@ type BMCService struct {
 	mqLogLevel          string
 	machineTopic        string
 	machineTopicTTL     time.Duration
+	producerLock        sync.Mutex
+	producer            *nsq.Producer
+	// reporter runs the requested report cycles
+	reporter Reporter
 }
 
-func New(log *slog.Logger, c *config.Config) *BMCService {
+// Reporter runs report cycles on demand and knows the bmcs of the machines
+type Reporter interface {
+	TriggerReport(macs, ips, uuids []string) error
+	// BMCMac returns the mac of the bmc of the machine with the given uuid, an empty string is returned if the machine is unknown
+	BMCMac(uuid string) string
+}
+
+func New(log *slog.Logger, c *config.Config, secrets credentials.Backend, usage *credentials.Usage) *BMCService {
 	b := &BMCService{
 		log:                 log,
+		ipmiPort:            c.IpmiPort,
+		usage:               usage,
+		secrets:             secrets,
 		mqAddress:           c.MQAddress,
 		mqCACertFile:        c.MQCACertFile,
 		mqClientCertFile:    c.MQClientCertFile,
@@ -39,6 +60,11 @@ func New(log *slog.Logger, c *config.Config) *BMCService {
 	return b
 }
 
+// SetReporter sets the reporter which runs the report cycles requested by a report command.
+func (b *BMCService) SetReporter(r Reporter) {
+	b.reporter = r
+}
+
 type MachineEvent struct {
 	Type         EventType           `json:"type,omitempty"`
 	OldMachineID string              `json:"old,omitempty"`
@@ -50,10 +76,12 @@ type MachineExecCommand struct {
 	Command         MachineCommand  `json:"cmd,omitempty"`
 	IPMI            *IPMI           `json:"ipmi,omitempty"`
 	FirmwareUpdate  *FirmwareUpdate `json:"firmwareupdate,omitempty"`
+	Report          *ReportTrigger  `json:"report,omitempty"`
 }
 
 type IPMI struct {
-	// Address is host:port of the connection to the ipmi BMC, host can be either a ip address or a hostname
+	// Address is host:port of the connection to the ipmi BMC, host can be either a ip address or a hostname,
+	// ipv6 addresses must be enclosed in brackets if a port is given, without port the configured ipmi port is used
 	Address  string `json:"address"`
 	User     string `json:"user"`
 	Password string `json:"password"`
@@ -65,6 +93,13 @@ type FirmwareUpdate struct {
 	URL  string `json:"url"`
 }
 
+// ReportTrigger limits a requested report cycle to the given devices
+type ReportTrigger struct {
+	Macs  []string `json:"macs,omitempty"`
+	Ips   []string `json:"ips,omitempty"`
+	UUIDs []string `json:"uuids,omitempty"`
+}
+
 type Fru struct {
 	BoardPartNumber string `json:"board_part_number"`
 }
@@ -84,6 +119,7 @@ const (
 	ChassisIdentifyLEDOnCmd  MachineCommand = "LED-ON"
 	ChassisIdentifyLEDOffCmd MachineCommand = "LED-OFF"
 	UpdateFirmwareCmd        MachineCommand = "UPDATE-FIRMWARE"
+	ReportCmd                MachineCommand = "REPORT"
 )
 
 type EventType string
@@ -96,19 +132,41 @@ const (
 	Command EventType = "command"
 )
 
-func (b *BMCService) outBand(ipmi *IPMI) (hal.OutBand, error) {
-	host, portString, found := strings.Cut(ipmi.Address, ":")
-	if !found {
-		portString = "623"
+// outBand connects to the bmc of the given machine, the returned function must be called when the command was executed.
+// The credentials of a provisioned bmc user are preferred over the credentials of the command.
+func (b *BMCService) outBand(machineID string, ipmi *IPMI) (hal.OutBand, func(), error) {
+	addr, err := address.Parse(ipmi.Address, b.ipmiPort)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse ipmi address: %w", err)
+	}
+	var mac string
+	if b.reporter != nil {
+		mac = b.reporter.BMCMac(machineID)
+	}
+	release := b.usage.Acquire(b.usage.Resolve(mac, addr.Host))
 
+	user, password := ipmi.User, ipmi.Password
+	if provisioned := b.provisioned(machineID, mac); provisioned != nil {
+		user, password = provisioned.User, provisioned.Password
 	}
-	port, err := strconv.Atoi(portString)
+
+	outBand, err := connect.OutBand(addr.OutBandHost(), addr.Port, user, password, halslog.New(b.log), new(time.Minute))
 	if err != nil {
-		return nil, fmt.Errorf("unable to convert port to an int %w", err)
+		release()
+		return nil, nil, err
+	}
+	return outBand, release, nil
+}
+
+// provisioned returns the credentials of the provisioned user of the bmc with the given mac, nil if there is none.
+func (b *BMCService) provisioned(machineID, mac string) *credentials.Credential {
+	if b.secrets == nil || mac == "" {
+		return nil
 	}
-	outBand, err := connect.OutBand(host, port, ipmi.User, ipmi.Password, halslog.New(b.log), new(time.Minute))
+	provisioned, err := b.secrets.Get(mac)
 	if err != nil {
-		return nil, err
+		b.log.Error("unable to read provisioned bmc credentials", "machineID", machineID, "mac", mac, "error", err)
+		return nil
 	}
-	return outBand, nil
+	return provisioned
 }
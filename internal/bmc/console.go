@@ -8,12 +8,12 @@ import (
 	"io"
 	"log/slog"
 	"os"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/metal-stack/go-hal/connect"
 	halslog "github.com/metal-stack/go-hal/pkg/logger/slog"
+	"github.com/metal-stack/metal-bmc/internal/address"
+	"github.com/metal-stack/metal-bmc/internal/credentials"
 	"github.com/metal-stack/metal-bmc/pkg/config"
 	metalgo "github.com/metal-stack/metal-go"
 	"github.com/metal-stack/metal-go/api/client/machine"
@@ -23,14 +23,21 @@ import (
 )
 
 type console struct {
-	log       *slog.Logger
-	tlsConfig *tls.Config
-	port      int
-	hostKey   gossh.Signer
-	client    metalgo.Client
+	log                     *slog.Logger
+	tlsConfig               *tls.Config
+	port                    int
+	ipmiPort                int
+	websocketPort           int
+	websocketAllowedOrigins []string
+	hostKey                 gossh.Signer
+	client                  metalgo.Client
+	// secrets contains the credentials of provisioned bmc users, nil if provisioning is disabled
+	secrets credentials.Backend
+	// usage coordinates password rotations with console sessions
+	usage *credentials.Usage
 }
 
-func NewConsole(log *slog.Logger, client metalgo.Client, c config.Config) (*console, error) {
+func NewConsole(log *slog.Logger, client metalgo.Client, c config.Config, secrets credentials.Backend, usage *credentials.Usage) (*console, error) {
 
 	caCert, err := os.ReadFile(c.ConsoleCACertFile)
 	if err != nil {
@@ -62,11 +69,16 @@ func NewConsole(log *slog.Logger, client metalgo.Client, c config.Config) (*cons
 	}
 
 	return &console{
-		log:       log,
-		tlsConfig: tlsConfig,
-		port:      c.ConsolePort,
-		hostKey:   hostKey,
-		client:    client,
+		log:                     log,
+		tlsConfig:               tlsConfig,
+		port:                    c.ConsolePort,
+		ipmiPort:                c.IpmiPort,
+		websocketPort:           c.ConsoleWebsocketPort,
+		websocketAllowedOrigins: c.ConsoleWebsocketAllowedOrigins,
+		hostKey:                 hostKey,
+		client:                  client,
+		secrets:                 secrets,
+		usage:                   usage,
 	}, nil
 }
 
@@ -88,8 +100,11 @@ func (c *console) ListenAndServe() error {
 // FIXME broken error handling, should also be printed to the session
 func (c *console) sessionHandler(s ssh.Session) {
 	c.log.Info("ssh session handler called", "machineID", s.User())
-	machineID := s.User()
+	c.serve(s.User(), s)
+}
 
+// serve looks up the ipmi details of the given machine and proxies its serial console to the session.
+func (c *console) serve(machineID string, s ssh.Session) {
 	resp, err := c.client.Machine().FindIPMIMachine(machine.NewFindIPMIMachineParams().WithID(machineID), nil)
 	if err != nil || resp.Payload == nil || resp.Payload.Ipmi == nil {
 		c.log.Error("failed to receive IPMI data", "machineID", machineID, "error", err)
@@ -111,20 +126,43 @@ func (c *console) sessionHandler(s ssh.Session) {
 		c.log.Warn("failed to write to console", "machineID", machineID)
 	}
 
-	host, portStr, found := strings.Cut(*metalIPMI.Address, ":")
-	if !found {
-		c.log.Error("invalid ipmi address", "address", *metalIPMI.Address)
-		return
-	}
-	port, err := strconv.Atoi(portStr)
+	addr, err := address.Parse(*metalIPMI.Address, c.ipmiPort)
 	if err != nil {
-		c.log.Error("invalid port", "port", port, "address", *metalIPMI.Address)
+		c.log.Error("invalid ipmi address", "address", *metalIPMI.Address, "error", err)
 		return
 	}
 
-	ob, err := connect.OutBand(host, port, *metalIPMI.User, *metalIPMI.Password, halslog.New(c.log), new(time.Minute))
+	// the credentials must not be rotated while the session is open
+	var mac string
+	if metalIPMI.Mac != nil {
+		mac = *metalIPMI.Mac
+	}
+	release := c.usage.Acquire(c.usage.Resolve(mac, addr.Host))
+	defer release()
+
+	user, password := *metalIPMI.User, *metalIPMI.Password
+	if c.secrets != nil && metalIPMI.Mac != nil {
+		provisioned, err := c.secrets.Get(*metalIPMI.Mac)
+		if err != nil {
+			c.log.Error("unable to read provisioned bmc credentials", "machineID", machineID, "error", err)
+		}
+		if provisioned != nil {
+			user, password = provisioned.User, provisioned.Password
+		}
+	}
+
+	if addr.IsIPv6() {
+		// go-hal passes the same host to redfish, which requires brackets, and to ipmitool, which does not accept them
+		c.log.Error("serial console of bmcs with ipv6 addresses is not supported", "machineID", machineID, "host", addr.Host)
+		_, err = io.WriteString(s, fmt.Sprintf("The console of %q is not supported, its bmc has the ipv6 address %s\n", machineID, addr.Host))
+		if err != nil {
+			c.log.Warn("failed to write to console", "machineID", machineID)
+		}
+		return
+	}
+	ob, err := connect.OutBand(addr.OutBandHost(), addr.Port, user, password, halslog.New(c.log), new(time.Minute))
 	if err != nil {
-		c.log.Error("failed to out-band connect", "host", host, "port", port, "machineID", machineID, "ipmiuser", *metalIPMI.User)
+		c.log.Error("failed to out-band connect", "host", addr.Host, "port", addr.Port, "machineID", machineID, "ipmiuser", user)
 		return
 	}
 
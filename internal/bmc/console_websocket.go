@@ -0,0 +1,321 @@
+package bmc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/gorilla/websocket"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultWebsocketTerm   = "xterm-256color"
+	websocketResizeMessage = "resize"
+)
+
+// websocketControlMessage is sent by the client as text message, binary messages are passed to the console as input.
+type websocketControlMessage struct {
+	Type string `json:"type"`
+	Cols int    `json:"cols"`
+	Rows int    `json:"rows"`
+}
+
+// ListenAndServeWebsocket starts a https server and serves the console of a machine over a websocket
+// at /machines/{id}/console, it uses the same mTLS configuration as the ssh server.
+func (c *console) ListenAndServeWebsocket() error {
+	upgrader := &websocket.Upgrader{}
+	if len(c.websocketAllowedOrigins) > 0 {
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			return slices.Contains(c.websocketAllowedOrigins, r.Header.Get("Origin"))
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /machines/{id}/console", func(w http.ResponseWriter, r *http.Request) {
+		c.websocketHandler(upgrader, w, r)
+	})
+
+	addr := fmt.Sprintf(":%d", c.websocketPort)
+	listener, err := tls.Listen("tcp", addr, c.tlsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create listener: %w", err)
+	}
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: time.Minute,
+		// console sessions are long lived, only idle keep-alive connections are closed
+		IdleTimeout: 2 * time.Minute,
+	}
+	c.log.Info("starting websocket server", "address", addr)
+	return server.Serve(listener)
+}
+
+func (c *console) websocketHandler(upgrader *websocket.Upgrader, w http.ResponseWriter, r *http.Request) {
+	machineID := r.PathValue("id")
+	c.log.Info("websocket session handler called", "machineID", machineID)
+
+	ptyReq := ssh.Pty{
+		Term: r.URL.Query().Get("term"),
+	}
+	if ptyReq.Term == "" {
+		ptyReq.Term = defaultWebsocketTerm
+	}
+	if cols, err := strconv.Atoi(r.URL.Query().Get("cols")); err == nil {
+		ptyReq.Window.Width = cols
+	}
+	if rows, err := strconv.Atoi(r.URL.Query().Get("rows")); err == nil {
+		ptyReq.Window.Height = rows
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		c.log.Error("failed to upgrade to websocket", "machineID", machineID, "error", err)
+		return
+	}
+
+	s := newWebsocketSession(r.Context(), conn, machineID, ptyReq)
+	defer func() {
+		_ = s.Close()
+	}()
+
+	c.serve(machineID, s)
+}
+
+// websocketSession adapts a websocket connection to a ssh.Session, which is required by the go-hal console implementations.
+type websocketSession struct {
+	conn   *websocket.Conn
+	ctx    *websocketContext
+	user   string
+	pty    ssh.Pty
+	winCh  chan ssh.Window
+	reader io.Reader
+
+	writeLock sync.Mutex
+	winLock   sync.Mutex
+	closeOnce sync.Once
+}
+
+func newWebsocketSession(ctx context.Context, conn *websocket.Conn, user string, pty ssh.Pty) *websocketSession {
+	wctx, cancel := context.WithCancel(ctx)
+	return &websocketSession{
+		conn: conn,
+		ctx: &websocketContext{
+			Context: wctx,
+			cancel:  cancel,
+			user:    user,
+			remote:  conn.RemoteAddr(),
+			local:   conn.LocalAddr(),
+		},
+		user:  user,
+		pty:   pty,
+		winCh: make(chan ssh.Window, 1),
+	}
+}
+
+// Read returns the input of binary messages, text messages are handled as control messages.
+func (s *websocketSession) Read(p []byte) (int, error) {
+	for {
+		if s.reader != nil {
+			n, err := s.reader.Read(p)
+			if errors.Is(err, io.EOF) {
+				s.reader = nil
+				if n == 0 {
+					continue
+				}
+				return n, nil
+			}
+			return n, err
+		}
+
+		messageType, r, err := s.conn.NextReader()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+
+		switch messageType {
+		case websocket.BinaryMessage:
+			s.reader = r
+		case websocket.TextMessage:
+			var msg websocketControlMessage
+			err := json.NewDecoder(r).Decode(&msg)
+			if err != nil || msg.Type != websocketResizeMessage {
+				continue
+			}
+			s.resize(ssh.Window{Width: msg.Cols, Height: msg.Rows})
+		}
+	}
+}
+
+func (s *websocketSession) resize(win ssh.Window) {
+	s.winLock.Lock()
+	defer s.winLock.Unlock()
+	if s.ctx.Err() != nil {
+		return
+	}
+	// only the latest window size is of interest
+	select {
+	case <-s.winCh:
+	default:
+	}
+	s.winCh <- win
+}
+
+func (s *websocketSession) Write(p []byte) (int, error) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	err := s.conn.WriteMessage(websocket.BinaryMessage, p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *websocketSession) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.winLock.Lock()
+		s.ctx.cancel()
+		close(s.winCh)
+		s.winLock.Unlock()
+		err = s.conn.Close()
+	})
+	return err
+}
+
+func (s *websocketSession) CloseWrite() error {
+	return nil
+}
+
+func (s *websocketSession) SendRequest(name string, wantReply bool, payload []byte) (bool, error) {
+	return false, nil
+}
+
+func (s *websocketSession) Stderr() io.ReadWriter {
+	return s
+}
+
+func (s *websocketSession) User() string {
+	return s.user
+}
+
+func (s *websocketSession) RemoteAddr() net.Addr {
+	return s.conn.RemoteAddr()
+}
+
+func (s *websocketSession) LocalAddr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+func (s *websocketSession) Environ() []string {
+	return nil
+}
+
+// Exit sends a close message carrying the exit code and closes the connection.
+func (s *websocketSession) Exit(code int) error {
+	closeCode := websocket.CloseNormalClosure
+	if code != 0 {
+		closeCode = websocket.CloseInternalServerErr
+	}
+	s.writeLock.Lock()
+	err := s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, fmt.Sprintf("exit status %d", code)))
+	s.writeLock.Unlock()
+	return errors.Join(err, s.Close())
+}
+
+func (s *websocketSession) Command() []string {
+	return nil
+}
+
+func (s *websocketSession) RawCommand() string {
+	return ""
+}
+
+func (s *websocketSession) Subsystem() string {
+	return ""
+}
+
+func (s *websocketSession) PublicKey() ssh.PublicKey {
+	return nil
+}
+
+func (s *websocketSession) Context() ssh.Context {
+	return s.ctx
+}
+
+func (s *websocketSession) Permissions() ssh.Permissions {
+	return ssh.Permissions{Permissions: &gossh.Permissions{}}
+}
+
+func (s *websocketSession) Pty() (ssh.Pty, <-chan ssh.Window, bool) {
+	return s.pty, s.winCh, true
+}
+
+func (s *websocketSession) Signals(c chan<- ssh.Signal) {}
+
+func (s *websocketSession) Break(c chan<- bool) {}
+
+// websocketContext implements ssh.Context for a websocket session.
+type websocketContext struct {
+	context.Context
+	sync.Mutex
+
+	cancel context.CancelFunc
+	user   string
+	remote net.Addr
+	local  net.Addr
+
+	values sync.Map
+}
+
+func (c *websocketContext) Value(key any) any {
+	if v, ok := c.values.Load(key); ok {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+func (c *websocketContext) User() string {
+	return c.user
+}
+
+func (c *websocketContext) SessionID() string {
+	return ""
+}
+
+func (c *websocketContext) ClientVersion() string {
+	return ""
+}
+
+func (c *websocketContext) ServerVersion() string {
+	return ""
+}
+
+func (c *websocketContext) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *websocketContext) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *websocketContext) Permissions() *ssh.Permissions {
+	return &ssh.Permissions{Permissions: &gossh.Permissions{}}
+}
+
+func (c *websocketContext) SetValue(key, value any) {
+	c.values.Store(key, value)
+}
@@ -0,0 +1,80 @@
+package bmc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_websocketSession(t *testing.T) {
+	sessions := make(chan *websocketSession, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := &websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		// the session outlives the handler in this test
+		sessions <- newWebsocketSession(context.Background(), conn, "machine", ssh.Pty{Term: defaultWebsocketTerm})
+	}))
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	var s *websocketSession
+	select {
+	case s = <-sessions:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no websocket session was created")
+	}
+
+	pty, winCh, ok := s.Pty()
+	require.True(t, ok)
+	assert.Equal(t, defaultWebsocketTerm, pty.Term)
+	assert.Equal(t, "machine", s.User())
+
+	// control messages resize the window, binary messages are the input of the console
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(`{"type":"resize","cols":120,"rows":40}`)))
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(`not json`)))
+	require.NoError(t, client.WriteMessage(websocket.BinaryMessage, []byte("ls\n")))
+
+	buf := make([]byte, 16)
+	n, err := s.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ls\n", string(buf[:n]))
+	assert.Equal(t, ssh.Window{Width: 120, Height: 40}, <-winCh)
+
+	_, err = s.Write([]byte("output"))
+	require.NoError(t, err)
+	messageType, data, err := client.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, messageType)
+	assert.Equal(t, "output", string(data))
+
+	// the exit status is sent as close message
+	require.NoError(t, s.Exit(1))
+	_, _, err = client.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseInternalServerErr), "unexpected error %v", err)
+	assert.Contains(t, err.Error(), "exit status 1")
+
+	// the context is cancelled and the window channel is closed
+	require.ErrorIs(t, s.Context().Err(), context.Canceled)
+	_, open := <-winCh
+	assert.False(t, open)
+	require.NoError(t, s.Close())
+
+	_, err = s.Read(buf)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, io.EOF)
+}
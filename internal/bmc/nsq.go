@@ -17,43 +17,11 @@ const (
 )
 
 func (b *BMCService) InitConsumer() error {
-	caCertRaw, err := os.ReadFile(b.mqCACertFile)
-	if err != nil {
-		return fmt.Errorf("failed to read ca cert: %w", err)
-	}
-
-	caCertPool, err := x509.SystemCertPool()
+	config, err := b.nsqConfig()
 	if err != nil {
 		return err
 	}
 
-	ok := caCertPool.AppendCertsFromPEM(caCertRaw)
-	if !ok {
-		return fmt.Errorf("unable to add ca to cert pool")
-	}
-
-	cert, err := tls.LoadX509KeyPair(b.mqClientCertFile, b.mqClientCertKeyFile)
-	if err != nil {
-		return err
-	}
-
-	config := nsq.NewConfig()
-	config.TlsConfig = &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		ClientCAs:    caCertPool,
-		RootCAs:      caCertPool,
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		MinVersion:   tls.VersionTLS12,
-	}
-	config.TlsV1 = true
-
-	// Deadlines for network reads and writes
-	config.ReadTimeout = 10 * time.Second
-	config.WriteTimeout = 10 * time.Second
-
-	// Duration of time between heartbeats. This must be less than ReadTimeout
-	config.HeartbeatInterval = 5 * time.Second
-
 	// Maximum duration when REQueueing (for doubling of deferred requeue)
 	config.MaxRequeueDelay = 5 * time.Second
 	config.DefaultRequeueDelay = 3 * time.Second
@@ -92,14 +60,20 @@ func (b *BMCService) HandleMessage(message *nsq.Message) error {
 
 	b.log.Info("got message from nsq", "topic", b.machineTopic, "event", event, "attempt", message.Attempts)
 
+	if event.Type == Command && event.Cmd != nil && event.Cmd.Command == ReportCmd {
+		// the reporter connects to the bmcs itself
+		return b.TriggerReport(&event)
+	}
+
 	if event.Cmd.IPMI == nil {
 		return fmt.Errorf("event does not contain ipmi details:%v", event)
 	}
-	outBand, err := b.outBand(event.Cmd.IPMI)
+	outBand, release, err := b.outBand(event.Cmd.TargetMachineID, event.Cmd.IPMI)
 	if err != nil {
 		b.log.Error("error creating outband connection", "error", err)
 		return err
 	}
+	defer release()
 
 	switch event.Type {
 	case Delete:
@@ -148,3 +122,77 @@ func (b *BMCService) HandleMessage(message *nsq.Message) error {
 	}
 	return nil
 }
+
+// Publish publishes the given message to the topic, the producer is created with the first message.
+// The producer connects to nsqd on demand, so an unreachable nsqd only fails the publishing of events.
+func (b *BMCService) Publish(topic string, body []byte) error {
+	producer, err := b.getProducer()
+	if err != nil {
+		return fmt.Errorf("unable to create event producer: %w", err)
+	}
+	return producer.Publish(topic, body)
+}
+
+func (b *BMCService) getProducer() (*nsq.Producer, error) {
+	b.producerLock.Lock()
+	defer b.producerLock.Unlock()
+	if b.producer != nil {
+		return b.producer, nil
+	}
+
+	config, err := b.nsqConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := nsq.NewProducer(b.mqAddress, config)
+	if err != nil {
+		return nil, err
+	}
+	producer.SetLogger(nsqLogger{log: b.log}, nsqMapLevel(b.log))
+
+	b.producer = producer
+	return producer, nil
+}
+
+// nsqConfig returns the configuration which is shared by the consumer and the producer.
+func (b *BMCService) nsqConfig() (*nsq.Config, error) {
+	caCertRaw, err := os.ReadFile(b.mqCACertFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca cert: %w", err)
+	}
+
+	caCertPool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, err
+	}
+
+	ok := caCertPool.AppendCertsFromPEM(caCertRaw)
+	if !ok {
+		return nil, fmt.Errorf("unable to add ca to cert pool")
+	}
+
+	cert, err := tls.LoadX509KeyPair(b.mqClientCertFile, b.mqClientCertKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := nsq.NewConfig()
+	config.TlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    caCertPool,
+		RootCAs:      caCertPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+	config.TlsV1 = true
+
+	// Deadlines for network reads and writes
+	config.ReadTimeout = 10 * time.Second
+	config.WriteTimeout = 10 * time.Second
+
+	// Duration of time between heartbeats. This must be less than ReadTimeout
+	config.HeartbeatInterval = 5 * time.Second
+
+	return config, nil
+}
@@ -0,0 +1,26 @@
+package bmc
+
+import (
+	"fmt"
+)
+
+// TriggerReport requests a report cycle for the target machine and the devices of the report trigger,
+// without any of them all devices of the partition are reported.
+func (b *BMCService) TriggerReport(event *MachineEvent) error {
+	b.log.Info("trigger report", "event", event)
+	if b.reporter == nil {
+		return fmt.Errorf("reporter is not initialized")
+	}
+
+	var macs, ips, uuids []string
+	if event.Cmd.TargetMachineID != "" {
+		uuids = append(uuids, event.Cmd.TargetMachineID)
+	}
+	if report := event.Cmd.Report; report != nil {
+		macs = report.Macs
+		ips = report.Ips
+		uuids = append(uuids, report.UUIDs...)
+	}
+
+	return b.reporter.TriggerReport(macs, ips, uuids)
+}
@@ -0,0 +1,232 @@
+package credentials
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// BackendFile stores credentials in a json file
+	BackendFile = "file"
+
+	passwordLower  = "abcdefghijklmnopqrstuvwxyz"
+	passwordUpper  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordDigits = "0123456789"
+)
+
+// Credential of a bmc user which was provisioned by metal-bmc
+type Credential struct {
+	User      string    `json:"user"`
+	Password  string    `json:"password"`
+	CreatedAt time.Time `json:"created_at"`
+	// RotatedAt is the time the password was changed the last time
+	RotatedAt time.Time `json:"rotated_at,omitzero"`
+	// PreviousPassword is only set while a rotation is in progress or could not be completed,
+	// the bmc accepts either the password or the previous password
+	PreviousPassword string `json:"previous_password,omitempty"`
+}
+
+// ChangedAt returns the time the password was set.
+func (c *Credential) ChangedAt() time.Time {
+	if c.RotatedAt.After(c.CreatedAt) {
+		return c.RotatedAt
+	}
+	return c.CreatedAt
+}
+
+// Backend stores the provisioned credentials per mac address of a bmc
+type Backend interface {
+	// Get returns the credential of the bmc with the given mac, nil is returned if there is none
+	Get(mac string) (*Credential, error)
+	// Put stores the credential of the bmc with the given mac
+	Put(mac string, c Credential) error
+	// Delete removes the credential of the bmc with the given mac
+	Delete(mac string) error
+}
+
+// NewBackend creates the secret backend of the given kind.
+func NewBackend(kind, path string) (Backend, error) {
+	switch kind {
+	case BackendFile:
+		if path == "" {
+			return nil, fmt.Errorf("file backend requires a path")
+		}
+		return &fileBackend{path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown secret backend %q", kind)
+	}
+}
+
+// fileBackend stores all credentials in a single json file which is only readable by the owner
+type fileBackend struct {
+	path string
+	lock sync.Mutex
+}
+
+func (f *fileBackend) Get(mac string) (*Credential, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	credentials, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := credentials[mac]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (f *fileBackend) Put(mac string, c Credential) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	credentials, err := f.read()
+	if err != nil {
+		return err
+	}
+	credentials[mac] = c
+	return f.write(credentials)
+}
+
+func (f *fileBackend) Delete(mac string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	credentials, err := f.read()
+	if err != nil {
+		return err
+	}
+	delete(credentials, mac)
+	return f.write(credentials)
+}
+
+func (f *fileBackend) read() (map[string]Credential, error) {
+	credentials := map[string]Credential{}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return credentials, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &credentials)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse credentials file: %w", err)
+	}
+	return credentials, nil
+}
+
+func (f *fileBackend) write(credentials map[string]Credential) error {
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// GeneratePassword returns a random password of the given length which contains lower and upper case letters and digits.
+func GeneratePassword(length int) (string, error) {
+	if length < 3 {
+		return "", fmt.Errorf("password length must be at least 3")
+	}
+
+	all := passwordLower + passwordUpper + passwordDigits
+	password := make([]byte, length)
+	for i := range password {
+		charset := all
+		// ensure every character class is contained at least once
+		switch i {
+		case 0:
+			charset = passwordLower
+		case 1:
+			charset = passwordUpper
+		case 2:
+			charset = passwordDigits
+		}
+		c, err := randomChar(charset)
+		if err != nil {
+			return "", err
+		}
+		password[i] = c
+	}
+
+	// shuffle to not have the character classes at fixed positions
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+
+	return string(password), nil
+}
+
+// GenerateUserName returns the given prefix followed by random lower case letters and digits.
+func GenerateUserName(prefix string, length int) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	for range length {
+		c, err := randomChar(passwordLower + passwordDigits)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String(), nil
+}
+
+// IsGeneratedUserName returns true if the given name could have been returned by GenerateUserName with the given prefix and length.
+func IsGeneratedUserName(name, prefix string, length int) bool {
+	suffix, ok := strings.CutPrefix(name, prefix)
+	if !ok || len(suffix) != length {
+		return false
+	}
+	for _, c := range []byte(suffix) {
+		if !strings.ContainsRune(passwordLower+passwordDigits, rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func randomChar(charset string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if err != nil {
+		return 0, err
+	}
+	return charset[n.Int64()], nil
+}
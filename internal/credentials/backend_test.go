@@ -0,0 +1,79 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipmi-credentials.json")
+	b, err := NewBackend(BackendFile, path)
+	require.NoError(t, err)
+
+	c, err := b.Get("ac:1f:6b:35:ac:62")
+	require.NoError(t, err)
+	require.Nil(t, c)
+
+	want := Credential{User: "metalabc", Password: "secret", CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, b.Put("ac:1f:6b:35:ac:62", want))
+	require.NoError(t, b.Put("ac:1f:6b:35:ab:2d", Credential{User: "metaldef", Password: "other"}))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// a new backend reads the stored credentials
+	b, err = NewBackend(BackendFile, path)
+	require.NoError(t, err)
+	c, err = b.Get("ac:1f:6b:35:ac:62")
+	require.NoError(t, err)
+	if diff := cmp.Diff(&want, c); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	require.NoError(t, b.Delete("ac:1f:6b:35:ac:62"))
+	c, err = b.Get("ac:1f:6b:35:ac:62")
+	require.NoError(t, err)
+	require.Nil(t, c)
+	c, err = b.Get("ac:1f:6b:35:ab:2d")
+	require.NoError(t, err)
+	require.NotNil(t, c)
+}
+
+func TestNewBackend(t *testing.T) {
+	_, err := NewBackend("vault", "")
+	require.EqualError(t, err, `unknown secret backend "vault"`)
+	_, err = NewBackend(BackendFile, "")
+	require.EqualError(t, err, "file backend requires a path")
+}
+
+func TestGeneratePassword(t *testing.T) {
+	for range 100 {
+		password, err := GeneratePassword(20)
+		require.NoError(t, err)
+		require.Len(t, password, 20)
+		require.True(t, strings.ContainsAny(password, passwordLower))
+		require.True(t, strings.ContainsAny(password, passwordUpper))
+		require.True(t, strings.ContainsAny(password, passwordDigits))
+	}
+
+	_, err := GeneratePassword(2)
+	require.Error(t, err)
+}
+
+func TestGenerateUserName(t *testing.T) {
+	user, err := GenerateUserName("metal", 8)
+	require.NoError(t, err)
+	require.Len(t, user, 13)
+	require.True(t, strings.HasPrefix(user, "metal"))
+	require.True(t, IsGeneratedUserName(user, "metal", 8))
+	require.False(t, IsGeneratedUserName(user, "metal", 7))
+	require.False(t, IsGeneratedUserName("metalADMIN123", "metal", 8))
+	require.False(t, IsGeneratedUserName("ADMIN", "metal", 8))
+}
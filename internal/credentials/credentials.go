@@ -0,0 +1,190 @@
+package credentials
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Set is a set of ipmi credentials, which is optionally scoped to certain devices.
+// If more than one scope is given, a device must match all of them.
+type Set struct {
+	// Name identifies the set, it is used to remember which set worked for a device
+	Name     string `json:"name" yaml:"name"`
+	User     string `json:"user" yaml:"user"`
+	Password string `json:"password" yaml:"password"`
+	// CIDRs limits the set to devices whose ip is in one of the given networks
+	CIDRs []string `json:"cidrs,omitempty" yaml:"cidrs,omitempty"`
+	// OUIs limits the set to devices whose mac address starts with one of the given prefixes, e.g. "ac:1f:6b"
+	OUIs []string `json:"ouis,omitempty" yaml:"ouis,omitempty"`
+	// Manufacturers limits the set to devices whose fru manufacturer contains one of the given names,
+	// it is ignored as long as the manufacturer of a device is not known.
+	Manufacturers []string `json:"manufacturers,omitempty" yaml:"manufacturers,omitempty"`
+
+	prefixes []netip.Prefix
+}
+
+// Sets is an ordered list of credential sets
+type Sets []Set
+
+// Device describes a device for which credentials are looked up
+type Device struct {
+	Mac string
+	Ip  string
+	// Manufacturer is the fru manufacturer if it is already known
+	Manufacturer string
+}
+
+// LoadFile reads the credential sets from a yaml or json file.
+func LoadFile(path string) (Sets, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sets Sets
+	err = yaml.Unmarshal(data, &sets)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse credentials file: %w", err)
+	}
+
+	return sets, nil
+}
+
+// Validate checks all sets, assigns names to unnamed sets and prepares them for matching.
+func (s Sets) Validate() error {
+	names := map[string]bool{}
+	for i := range s {
+		set := &s[i]
+		if set.Name == "" {
+			set.Name = fmt.Sprintf("set-%d", i)
+		}
+		if names[set.Name] {
+			return fmt.Errorf("credential set name %q is not unique", set.Name)
+		}
+		names[set.Name] = true
+
+		if set.User == "" {
+			return fmt.Errorf("credential set %q has no user", set.Name)
+		}
+
+		set.prefixes = nil
+		for _, cidr := range set.CIDRs {
+			pfx, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return fmt.Errorf("credential set %q has an invalid cidr: %w", set.Name, err)
+			}
+			set.prefixes = append(set.prefixes, pfx)
+		}
+
+		for i, oui := range set.OUIs {
+			normalized, err := normalizeOUI(oui)
+			if err != nil {
+				return fmt.Errorf("credential set %q has an invalid oui: %w", set.Name, err)
+			}
+			set.OUIs[i] = normalized
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the set is applicable for the given device.
+func (s *Set) Matches(d Device) bool {
+	if len(s.prefixes) > 0 {
+		ip, err := netip.ParseAddr(d.Ip)
+		if err != nil {
+			return false
+		}
+		if !slices.ContainsFunc(s.prefixes, func(pfx netip.Prefix) bool { return pfx.Contains(ip) }) {
+			return false
+		}
+	}
+
+	if len(s.OUIs) > 0 {
+		mac, err := net.ParseMAC(d.Mac)
+		if err != nil || len(mac) < 3 {
+			return false
+		}
+		if !slices.Contains(s.OUIs, net.HardwareAddr(mac[:3]).String()) {
+			return false
+		}
+	}
+
+	if len(s.Manufacturers) > 0 && d.Manufacturer != "" {
+		manufacturer := strings.ToLower(d.Manufacturer)
+		if !slices.ContainsFunc(s.Manufacturers, func(m string) bool { return strings.Contains(manufacturer, strings.ToLower(m)) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s Set) String() string {
+	return s.Name
+}
+
+func normalizeOUI(oui string) (string, error) {
+	mac, err := net.ParseMAC(oui + ":00:00:00")
+	if err != nil {
+		return "", err
+	}
+	return net.HardwareAddr(mac[:3]).String(), nil
+}
+
+// Store returns the applicable credential sets for devices and remembers which set worked for a device.
+type Store struct {
+	sets Sets
+
+	lock    sync.Mutex
+	devices map[string]knownDevice
+}
+
+type knownDevice struct {
+	set          string
+	manufacturer string
+}
+
+// NewStore creates a store for the given sets, the sets must be validated before.
+func NewStore(sets Sets) *Store {
+	return &Store{
+		sets:    sets,
+		devices: make(map[string]knownDevice),
+	}
+}
+
+// Candidates returns the applicable credential sets for the device with the given mac and ip in the order
+// they should be tried. The set which worked the last time is returned first.
+func (s *Store) Candidates(mac, ip string) Sets {
+	s.lock.Lock()
+	known := s.devices[mac]
+	s.lock.Unlock()
+
+	device := Device{Mac: mac, Ip: ip, Manufacturer: known.manufacturer}
+
+	var candidates Sets
+	for _, set := range s.sets {
+		if !set.Matches(device) {
+			continue
+		}
+		if set.Name == known.set {
+			candidates = slices.Insert(candidates, 0, set)
+			continue
+		}
+		candidates = append(candidates, set)
+	}
+	return candidates
+}
+
+// Remember stores the set which worked for the device with the given mac together with its manufacturer.
+func (s *Store) Remember(mac, set, manufacturer string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.devices[mac] = knownDevice{set: set, manufacturer: manufacturer}
+}
@@ -0,0 +1,113 @@
+package credentials
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	err := os.WriteFile(path, []byte(`
+- name: supermicro
+  user: ADMIN
+  password: secret
+  ouis:
+    - AC:1F:6B
+- user: root
+  password: calvin
+  cidrs:
+    - 10.0.0.0/24
+  manufacturers:
+    - Dell
+`), 0600)
+	require.NoError(t, err)
+
+	sets, err := LoadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, sets.Validate())
+
+	want := Sets{
+		{Name: "supermicro", User: "ADMIN", Password: "secret", OUIs: []string{"ac:1f:6b"}},
+		{Name: "set-1", User: "root", Password: "calvin", CIDRs: []string{"10.0.0.0/24"}, Manufacturers: []string{"Dell"}},
+	}
+	if diff := cmp.Diff(want, sets, cmpopts.IgnoreUnexported(Set{})); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
+
+func TestSets_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sets    Sets
+		wantErr error
+	}{
+		{
+			name:    "duplicate names",
+			sets:    Sets{{Name: "a", User: "a"}, {Name: "a", User: "b"}},
+			wantErr: fmt.Errorf(`credential set name "a" is not unique`),
+		},
+		{
+			name:    "missing user",
+			sets:    Sets{{Name: "a"}},
+			wantErr: fmt.Errorf(`credential set "a" has no user`),
+		},
+		{
+			name:    "invalid oui",
+			sets:    Sets{{Name: "a", User: "a", OUIs: []string{"foo"}}},
+			wantErr: fmt.Errorf(`credential set "a" has an invalid oui: %w`, fmt.Errorf("address foo:00:00:00: invalid MAC address")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sets.Validate()
+			if diff := cmp.Diff(tt.wantErr, err, testcommon.ErrorStringComparer()); diff != "" {
+				t.Errorf("error diff = %s", diff)
+			}
+		})
+	}
+}
+
+func TestStore_Candidates(t *testing.T) {
+	sets := Sets{
+		{Name: "dell", User: "root", Manufacturers: []string{"dell"}},
+		{Name: "supermicro", User: "ADMIN", OUIs: []string{"ac:1f:6b"}},
+		{Name: "rack-1", User: "rack", CIDRs: []string{"10.0.1.0/24"}},
+		{Name: "default", User: "ADMIN"},
+	}
+	require.NoError(t, sets.Validate())
+
+	s := NewStore(sets)
+
+	names := func(sets Sets) []string {
+		var result []string
+		for _, set := range sets {
+			result = append(result, set.Name)
+		}
+		return result
+	}
+
+	// manufacturer is not known yet
+	want := []string{"dell", "supermicro", "default"}
+	if diff := cmp.Diff(want, names(s.Candidates("ac:1f:6b:35:ac:62", "10.0.0.1"))); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	want = []string{"dell", "rack-1", "default"}
+	if diff := cmp.Diff(want, names(s.Candidates("00:00:00:00:00:01", "10.0.1.1"))); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	// the working set is tried first and the manufacturer is considered
+	s.Remember("ac:1f:6b:35:ac:62", "default", "Supermicro")
+	want = []string{"default", "supermicro"}
+	if diff := cmp.Diff(want, names(s.Candidates("ac:1f:6b:35:ac:62", "10.0.0.1"))); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
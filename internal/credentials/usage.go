@@ -0,0 +1,76 @@
+package credentials
+
+import (
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// Usage coordinates connections to bmcs with the rotation of their credentials. Connections acquire
+// a bmc shared for their whole lifetime, a rotation requires exclusive access.
+// Bmcs are identified by their lease ip, which can differ from the address a command or console session connects to.
+type Usage struct {
+	lock  sync.Mutex
+	hosts map[string]*sync.RWMutex
+	// addresses contains the lease ip per bmc mac
+	addresses map[string]string
+}
+
+// NewUsage creates an empty usage registry.
+func NewUsage() *Usage {
+	return &Usage{
+		hosts:     make(map[string]*sync.RWMutex),
+		addresses: make(map[string]string),
+	}
+}
+
+// SetAddress remembers the lease ip of the bmc with the given mac.
+func (u *Usage) SetAddress(mac, ip string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.addresses[strings.ToLower(mac)] = ip
+}
+
+// Resolve returns the lease ip of the bmc with the given mac, the given host is returned if the lease ip is unknown.
+func (u *Usage) Resolve(mac, host string) string {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if ip, ok := u.addresses[strings.ToLower(mac)]; ok {
+		return ip
+	}
+	return host
+}
+
+// Acquire marks the bmc with the given lease ip as in use, it waits for a running rotation of its credentials.
+// The returned function must be called when the connection is closed.
+func (u *Usage) Acquire(host string) func() {
+	l := u.host(host)
+	l.RLock()
+	return l.RUnlock
+}
+
+// TryAcquireExclusive acquires the bmc with the given lease ip for a rotation of its credentials,
+// false is returned if the bmc is in use.
+func (u *Usage) TryAcquireExclusive(host string) (func(), bool) {
+	l := u.host(host)
+	if !l.TryLock() {
+		return nil, false
+	}
+	return l.Unlock, true
+}
+
+func (u *Usage) host(host string) *sync.RWMutex {
+	key := strings.ToLower(strings.Trim(host, "[]"))
+	if addr, err := netip.ParseAddr(key); err == nil {
+		key = addr.Unmap().String()
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	l, ok := u.hosts[key]
+	if !ok {
+		l = &sync.RWMutex{}
+		u.hosts[key] = l
+	}
+	return l
+}
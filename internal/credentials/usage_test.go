@@ -0,0 +1,40 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	u := NewUsage()
+
+	release := u.Acquire("10.0.0.1")
+	_, ok := u.TryAcquireExclusive("10.0.0.1")
+	require.False(t, ok, "bmc in use must not be rotated")
+
+	// other bmcs are not affected
+	releaseOther, ok := u.TryAcquireExclusive("10.0.0.2")
+	require.True(t, ok)
+	releaseOther()
+
+	release()
+	releaseRotation, ok := u.TryAcquireExclusive("10.0.0.1")
+	require.True(t, ok)
+	releaseRotation()
+
+	// ipv6 addresses are normalized
+	release = u.Acquire("[2001:DB8::1]")
+	_, ok = u.TryAcquireExclusive("2001:db8:0::1")
+	require.False(t, ok)
+	release()
+
+	// connections to another address of the bmc are resolved to its lease ip
+	u.SetAddress("AC:1F:6B:35:AC:62", "10.0.0.3")
+	require.Equal(t, "10.0.0.3", u.Resolve("ac:1f:6b:35:ac:62", "bmc.example.com"))
+	require.Equal(t, "bmc.example.com", u.Resolve("ac:1f:6b:35:ac:63", "bmc.example.com"))
+	release = u.Acquire(u.Resolve("ac:1f:6b:35:ac:62", "bmc.example.com"))
+	_, ok = u.TryAcquireExclusive("10.0.0.3")
+	require.False(t, ok)
+	release()
+}
@@ -7,14 +7,24 @@ import (
 	"github.com/metal-stack/go-hal"
 	"github.com/metal-stack/go-hal/connect"
 	halslog "github.com/metal-stack/go-hal/pkg/logger/slog"
+	"github.com/metal-stack/metal-bmc/internal/address"
 	"github.com/metal-stack/metal-go/api/models"
 )
 
+// EnrichWithBMCDetails reads the details of the bmc of the leased ip. If a mandatory stage fails an *EnrichmentError is returned,
+// failures of optional stages are added to the errors of the report item.
 func (i *ReportItem) EnrichWithBMCDetails(log *slog.Logger, ipmiPort int, ipmiUser, ipmiPassword string) error {
-	ob, err := connect.OutBand(i.Lease.Ip, ipmiPort, ipmiUser, ipmiPassword, halslog.New(log), new(time.Minute))
+	ap, err := i.Lease.AddrPort(ipmiPort)
+	if err != nil {
+		log.Error("invalid ip address of device bmc", "mac", i.Lease.Mac, "ip", i.Lease.Ip, "err", err)
+		return &EnrichmentError{Stage: StageConnect, Err: err}
+	}
+	addr := address.FromAddrPort(ap)
+
+	ob, err := connect.OutBand(addr.OutBandHost(), addr.Port, ipmiUser, ipmiPassword, halslog.New(log), new(time.Minute))
 	if err != nil {
 		log.Error("could not establish outband connection to device bmc", "mac", i.Lease.Mac, "ip", i.Lease.Ip, "err", err)
-		return err
+		return &EnrichmentError{Stage: StageConnect, Err: err}
 	}
 
 	bmcDetails, err := ob.BMCConnection().BMC()
@@ -32,13 +42,16 @@ func (i *ReportItem) EnrichWithBMCDetails(log *slog.Logger, ipmiPort int, ipmiUs
 		}
 	} else {
 		log.Warn("could not retrieve bmc details of device", "mac", i.Lease.Mac, "ip", i.Lease.Ip, "err", err)
-		return err
+		return &EnrichmentError{Stage: StageBMCDetails, Err: err}
 	}
 
 	powerState, err := ob.PowerState()
 	state := hal.PowerUnknownState.String()
 	if err == nil {
 		state = powerState.String()
+	} else {
+		log.Warn("could not determine power state of device", "mac", i.Lease.Mac, "ip", i.Lease.Ip, "err", err)
+		i.Errors = append(i.Errors, &EnrichmentError{Stage: StagePowerState, Err: err})
 	}
 	i.Powerstate = &state
 
@@ -72,7 +85,7 @@ func (i *ReportItem) EnrichWithBMCDetails(log *slog.Logger, ipmiPort int, ipmiUs
 		i.UUID = &str
 	} else {
 		log.Warn("could not determine uuid of device", "mac", i.Lease.Mac, "ip", i.Lease.Ip, "err", err)
-		return err
+		return &EnrichmentError{Stage: StageUUID, Err: err}
 	}
 	return nil
 }
@@ -0,0 +1,126 @@
+package leases
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type dnsmasqSource struct {
+	log  *slog.Logger
+	path string
+}
+
+// NewDnsmasqSource returns a lease source which reads the lease file of dnsmasq.
+func NewDnsmasqSource(log *slog.Logger, path string) LeaseSource {
+	return &dnsmasqSource{log: log, path: path}
+}
+
+func (s *dnsmasqSource) Leases() (Leases, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	leases, err := parseDnsmasqFile(s.log, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse lease file: %w", err)
+	}
+
+	return leases, nil
+}
+
+// parseDnsmasqFile parses a dnsmasq lease file. Dhcpv4 leases are written as "<expiry> <mac> <ip> <hostname> <client id>",
+// dhcpv6 leases follow the "duid <server duid>" line as "<expiry> <iaid> <ip> <hostname> <client duid>".
+// Dnsmasq does not remember the begin of a lease, an expiry of 0 means that the lease never expires.
+// The hostname and the client id of dhcpv4 leases are not used. An unterminated last line which can not be parsed is skipped.
+func parseDnsmasqFile(log *slog.Logger, data string) (Leases, error) {
+	var (
+		leases Leases
+		v6     bool
+	)
+
+	lines := strings.Split(data, "\n")
+	for i, line := range lines {
+		tokens := strings.Fields(line)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		if tokens[0] == "duid" {
+			// duid 00:01:00:01:2c:3f:1a:2b:ac:1f:6b:35:ac:70
+			v6 = true
+			continue
+		}
+
+		lease, err := parseDnsmasqLine(log, tokens, v6, i+1)
+		if err != nil {
+			if i == len(lines)-1 && !strings.HasSuffix(data, "\n") {
+				// dnsmasq terminates every line, the file is still being written
+				log.Warn("skipping truncated last line of lease file", "line", i+1, "error", err)
+				continue
+			}
+			return nil, err
+		}
+		if lease != nil {
+			leases = append(leases, *lease)
+		}
+	}
+
+	return leases, nil
+}
+
+// parseDnsmasqLine parses the tokens of a lease line, nil is returned for leases of unsupported hardware types.
+func parseDnsmasqLine(log *slog.Logger, tokens []string, v6 bool, lineNo int) (*Lease, error) {
+	if len(tokens) != 5 {
+		return nil, fmt.Errorf(`expecting "<expiry> <mac> <ip> <hostname> <client id>" on line %d, got: %s`, lineNo, strings.Join(tokens, " "))
+	}
+
+	expiry, err := strconv.ParseInt(tokens[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry on line %d: %w", lineNo, err)
+	}
+	end := infiniteLeaseEnd
+	if expiry != 0 {
+		end = time.Unix(expiry, 0).UTC()
+	}
+
+	if _, err := netip.ParseAddr(tokens[2]); err != nil {
+		return nil, fmt.Errorf("invalid ip address on line %d: %w", lineNo, err)
+	}
+
+	lease := &Lease{
+		Ip:  tokens[2],
+		End: end,
+	}
+
+	if v6 {
+		duid, err := parseLeaseString(tokens[4])
+		if err != nil {
+			return nil, fmt.Errorf("invalid duid on line %d: %w", lineNo, err)
+		}
+		ia := &identityAssociation{duid: duid}
+		mac, err := ia.mac()
+		if err != nil {
+			log.Warn("unable to determine mac address from duid, skipping entry", "line", lineNo, "duid", tokens[4], "error", err)
+			return nil, nil
+		}
+		lease.Mac = mac
+		lease.Duid = ia.duidString()
+		return lease, nil
+	}
+
+	hw, err := net.ParseMAC(tokens[1])
+	if err != nil {
+		// e.g. infiniband or other hardware types are written as <type>-<address>
+		log.Warn("unsupported mac address, skipping entry", "line", lineNo, "mac", tokens[1])
+		return nil, nil
+	}
+	lease.Mac = hw.String()
+	return lease, nil
+}
@@ -0,0 +1,96 @@
+package leases
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+var sampleDnsmasqContent = `3501577200 ac:1f:6b:35:ac:62 10.0.0.1 bmc-1 01:ac:1f:6b:35:ac:62
+0 AC:1F:6B:35:AB:2D 10.0.0.2 * *
+3501577200 20-00:01:02:03:04:05:06:07:08:09:0a:0b:0c:0d:0e:0f:10:11:12:13 10.0.0.3 * *
+duid 00:01:00:01:2c:3f:1a:2b:ac:1f:6b:35:ac:70
+3501577200 1236485442 fd00::1 bmc-4 00:03:00:01:ac:1f:6b:35:ac:63
+3501577200 1236485443 fd00::2 * 00:02:00:00:0a:4c:01:02:03
+`
+
+func Test_parseDnsmasqFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Leases
+		wantErr error
+	}{
+		{
+			name: "real example",
+			data: sampleDnsmasqContent,
+			want: Leases{
+				{
+					Mac: "ac:1f:6b:35:ac:62",
+					Ip:  "10.0.0.1",
+					End: time.Date(2080, 12, 16, 12, 20, 0, 0, time.UTC),
+				},
+				{
+					Mac: "ac:1f:6b:35:ab:2d",
+					Ip:  "10.0.0.2",
+					End: infiniteLeaseEnd,
+				},
+				{
+					Mac:  "ac:1f:6b:35:ac:63",
+					Ip:   "fd00::1",
+					Duid: "00:03:00:01:ac:1f:6b:35:ac:63",
+					End:  time.Date(2080, 12, 16, 12, 20, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name:    "missing field",
+			data:    "3501577200 ac:1f:6b:35:ac:62 10.0.0.1 bmc-1\n0 ac:1f:6b:35:ac:63 10.0.0.2 * *\n",
+			wantErr: fmt.Errorf(`expecting "<expiry> <mac> <ip> <hostname> <client id>" on line 1, got: 3501577200 ac:1f:6b:35:ac:62 10.0.0.1 bmc-1`),
+		},
+		{
+			name: "truncated last line is skipped",
+			data: "0 ac:1f:6b:35:ac:62 10.0.0.1 * *\n3501577200 ac:1f:6b:35:ac:63 10.0",
+			want: Leases{
+				{
+					Mac: "ac:1f:6b:35:ac:62",
+					Ip:  "10.0.0.1",
+					End: infiniteLeaseEnd,
+				},
+			},
+		},
+		{
+			name:    "invalid expiry",
+			data:    "never ac:1f:6b:35:ac:62 10.0.0.1 bmc-1 *\n",
+			wantErr: fmt.Errorf(`invalid expiry on line 1: strconv.ParseInt: parsing "never": invalid syntax`),
+		},
+		{
+			name:    "invalid ip",
+			data:    "0 ac:1f:6b:35:ac:62 10.0.0 bmc-1 *\n",
+			wantErr: fmt.Errorf(`invalid ip address on line 1: ParseAddr("10.0.0"): IPv4 address too short`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDnsmasqFile(slog.Default(), tt.data)
+			if tt.wantErr != nil {
+				require.EqualError(t, err, tt.wantErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_parseDnsmasqFile_infiniteLeaseIsActive(t *testing.T) {
+	l, err := parseDnsmasqFile(slog.Default(), "0 ac:1f:6b:35:ac:62 10.0.0.1 * *")
+	require.NoError(t, err)
+	require.Equal(t, l, l.FilterActive())
+}
@@ -0,0 +1,177 @@
+package leases
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// kea keeps the lease file of the running lease file cleanup in these files
+	keaCompletedSuffix = ".completed"
+	keaPreviousSuffix  = ".1"
+	keaCleanupSuffix   = ".2"
+
+	// keaStateDefault is the state of an assigned lease, declined, reclaimed and released leases have other states
+	keaStateDefault = 0
+)
+
+type keaMemfileSource struct {
+	log  *slog.Logger
+	path string
+}
+
+// NewKeaMemfileSource returns a lease source which reads the dhcpv4 csv lease file of the memfile backend of kea.
+func NewKeaMemfileSource(log *slog.Logger, path string) LeaseSource {
+	return &keaMemfileSource{log: log, path: path}
+}
+
+// Leases reads the leases the same way kea does on startup, the files of an unfinished lease file cleanup are read before the lease file.
+func (s *keaMemfileSource) Leases() (Leases, error) {
+	files := []string{s.path + keaCompletedSuffix}
+	if _, err := os.Stat(files[0]); errors.Is(err, fs.ErrNotExist) {
+		files = []string{s.path + keaCleanupSuffix, s.path + keaPreviousSuffix}
+	}
+
+	var data strings.Builder
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		data.Write(content)
+		data.WriteString("\n")
+	}
+
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	data.Write(content)
+
+	leases, err := parseKeaMemfile(s.log, data.String())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse lease file: %w", err)
+	}
+
+	return leases, nil
+}
+
+// parseKeaMemfile parses a kea dhcpv4 csv lease file. Kea appends every change of a lease to the file,
+// the last line of an address contains its current state. An unterminated last line which can not be parsed is skipped.
+func parseKeaMemfile(log *slog.Logger, data string) (Leases, error) {
+	var (
+		columns map[string]int
+		order   []string
+		byIp    = map[string]*Lease{}
+	)
+
+	lines := strings.Split(data, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		if fields[0] == "address" {
+			// every file starts with a header
+			columns = map[string]int{}
+			for idx, name := range fields {
+				columns[name] = idx
+			}
+			for _, name := range []string{"address", "hwaddr", "valid_lifetime", "expire"} {
+				if _, ok := columns[name]; !ok {
+					return nil, fmt.Errorf("missing column %q in header on line %d: %s", name, i+1, line)
+				}
+			}
+			continue
+		}
+		if columns == nil {
+			return nil, fmt.Errorf("missing header before line %d: %s", i+1, line)
+		}
+
+		ip, lease, err := parseKeaLine(columns, fields, i+1)
+		if err != nil {
+			if i == len(lines)-1 && !strings.HasSuffix(data, "\n") {
+				// kea terminates every line, the last line is still being written or its write was interrupted
+				log.Warn("skipping truncated last line of lease file", "line", i+1, "error", err)
+				continue
+			}
+			return nil, err
+		}
+
+		if _, ok := byIp[ip]; !ok {
+			order = append(order, ip)
+		}
+		byIp[ip] = lease
+	}
+
+	var leases Leases
+	for _, ip := range order {
+		if l := byIp[ip]; l != nil {
+			leases = append(leases, *l)
+		} else {
+			log.Debug("lease is not assigned anymore, skipping entry", "ip", ip)
+		}
+	}
+
+	return leases, nil
+}
+
+// parseKeaLine parses the fields of a line of a kea dhcpv4 csv lease file with the given columns.
+// The returned lease is nil if the address is not assigned anymore.
+func parseKeaLine(columns map[string]int, fields []string, lineNo int) (string, *Lease, error) {
+	if len(fields) < len(columns) {
+		return "", nil, fmt.Errorf("expecting %d columns on line %d, got: %s", len(columns), lineNo, strings.Join(fields, ","))
+	}
+
+	field := func(name string) string {
+		return fields[columns[name]]
+	}
+
+	ip := field("address")
+	if _, err := netip.ParseAddr(ip); err != nil {
+		return "", nil, fmt.Errorf("invalid ip address on line %d: %w", lineNo, err)
+	}
+
+	lifetime, err := strconv.ParseInt(field("valid_lifetime"), 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid valid_lifetime on line %d: %w", lineNo, err)
+	}
+	expire, err := strconv.ParseInt(field("expire"), 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid expire on line %d: %w", lineNo, err)
+	}
+
+	state := keaStateDefault
+	if _, ok := columns["state"]; ok {
+		state, err = strconv.Atoi(field("state"))
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid state on line %d: %w", lineNo, err)
+		}
+	}
+
+	mac := strings.ToLower(field("hwaddr"))
+	if state != keaStateDefault || mac == "" {
+		// the address is not assigned anymore
+		return ip, nil, nil
+	}
+
+	end := time.Unix(expire, 0).UTC()
+	return ip, &Lease{
+		Mac:   mac,
+		Ip:    ip,
+		Begin: end.Add(-time.Duration(lifetime) * time.Second),
+		End:   end,
+	}, nil
+}
@@ -0,0 +1,209 @@
+package leases
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	keaCommandGetAll  = "lease4-get-all"
+	keaCommandGetPage = "lease4-get-page"
+
+	// results of kea commands
+	keaResultSuccess = 0
+	keaResultEmpty   = 3
+)
+
+// KeaAPIConfig configures the access to the kea control agent
+type KeaAPIConfig struct {
+	// URL of the control agent
+	URL string
+	// User and Password are used for basic authentication if the user is set
+	User     string
+	Password string
+	// Timeout of a single request
+	Timeout time.Duration
+	// Subnets limits the leases to the given subnet ids, no subnet returns the leases of all subnets
+	Subnets []int64
+	// PageSize reads the leases in pages of the given size with lease4-get-page, 0 reads all leases at once with lease4-get-all
+	PageSize int
+}
+
+type keaAPISource struct {
+	log    *slog.Logger
+	cfg    KeaAPIConfig
+	client *http.Client
+}
+
+type keaCommand struct {
+	Command   string   `json:"command"`
+	Service   []string `json:"service"`
+	Arguments any      `json:"arguments,omitempty"`
+}
+
+type keaResponse struct {
+	Result    int    `json:"result"`
+	Text      string `json:"text"`
+	Arguments struct {
+		Leases []keaLease `json:"leases"`
+	} `json:"arguments"`
+}
+
+type keaLease struct {
+	IPAddress string `json:"ip-address"`
+	HWAddress string `json:"hw-address"`
+	ValidLft  int64  `json:"valid-lft"`
+	Cltt      int64  `json:"cltt"`
+	SubnetID  int64  `json:"subnet-id"`
+	State     int    `json:"state"`
+}
+
+// NewKeaAPISource returns a lease source which reads the dhcpv4 leases from the json api of the kea control agent.
+func NewKeaAPISource(log *slog.Logger, cfg KeaAPIConfig) LeaseSource {
+	return &keaAPISource{
+		log:    log,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+func (s *keaAPISource) Leases() (Leases, error) {
+	var (
+		kls []keaLease
+		err error
+	)
+	if s.cfg.PageSize > 0 {
+		kls, err = s.pages()
+	} else {
+		kls, err = s.all()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var leases Leases
+	for _, kl := range kls {
+		if len(s.cfg.Subnets) > 0 && !slices.Contains(s.cfg.Subnets, kl.SubnetID) {
+			continue
+		}
+		if kl.State != keaStateDefault || kl.HWAddress == "" {
+			s.log.Debug("lease is not assigned, skipping entry", "ip", kl.IPAddress, "state", kl.State)
+			continue
+		}
+		if _, err := netip.ParseAddr(kl.IPAddress); err != nil {
+			return nil, fmt.Errorf("invalid ip address of lease: %w", err)
+		}
+
+		begin := time.Unix(kl.Cltt, 0).UTC()
+		leases = append(leases, Lease{
+			Mac:   strings.ToLower(kl.HWAddress),
+			Ip:    kl.IPAddress,
+			Begin: begin,
+			End:   begin.Add(time.Duration(kl.ValidLft) * time.Second),
+		})
+	}
+
+	return leases, nil
+}
+
+func (s *keaAPISource) all() ([]keaLease, error) {
+	var args any
+	if len(s.cfg.Subnets) > 0 {
+		args = map[string]any{"subnets": s.cfg.Subnets}
+	}
+
+	resp, err := s.command(keaCommand{Command: keaCommandGetAll, Service: []string{"dhcp4"}, Arguments: args})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Arguments.Leases, nil
+}
+
+// pages reads the leases page by page, lease4-get-page does not support a subnet filter.
+func (s *keaAPISource) pages() ([]keaLease, error) {
+	var (
+		leases []keaLease
+		from   = "start"
+	)
+	for {
+		resp, err := s.command(keaCommand{
+			Command:   keaCommandGetPage,
+			Service:   []string{"dhcp4"},
+			Arguments: map[string]any{"from": from, "limit": s.cfg.PageSize},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		page := resp.Arguments.Leases
+		leases = append(leases, page...)
+		if len(page) < s.cfg.PageSize {
+			return leases, nil
+		}
+		from = page[len(page)-1].IPAddress
+	}
+}
+
+// command sends the command to the control agent, an empty result is no error.
+func (s *keaAPISource) command(cmd keaCommand) (*keaResponse, error) {
+	body, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.User != "" {
+		req.SetBasicAuth(s.cfg.User, s.cfg.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("kea command %s failed: %w", cmd.Command, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kea command %s failed with status %s", cmd.Command, resp.Status)
+	}
+
+	// the control agent answers with a response per service, a dhcp server answers with a single response
+	var raw json.RawMessage
+	err = json.NewDecoder(resp.Body).Decode(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode response of kea command %s: %w", cmd.Command, err)
+	}
+	var responses []keaResponse
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		err = json.Unmarshal(raw, &responses)
+	} else {
+		responses = make([]keaResponse, 1)
+		err = json.Unmarshal(raw, &responses[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode response of kea command %s: %w", cmd.Command, err)
+	}
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("empty response of kea command %s", cmd.Command)
+	}
+
+	r := &responses[0]
+	switch r.Result {
+	case keaResultSuccess, keaResultEmpty:
+		return r, nil
+	default:
+		return nil, fmt.Errorf("kea command %s failed with result %d: %s", cmd.Command, r.Result, r.Text)
+	}
+}
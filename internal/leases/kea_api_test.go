@@ -0,0 +1,161 @@
+package leases
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var keaAPILeases = []map[string]any{
+	{"ip-address": "10.0.0.1", "hw-address": "ac:1f:6b:35:ac:62", "valid-lft": 3600, "cltt": 3501577200, "subnet-id": 1, "state": 0},
+	{"ip-address": "10.0.0.2", "hw-address": "AC:1F:6B:35:AB:2D", "valid-lft": 3600, "cltt": 3501577200, "subnet-id": 2, "state": 0},
+	// declined
+	{"ip-address": "10.0.0.3", "hw-address": "", "valid-lft": 3600, "cltt": 3501577200, "subnet-id": 1, "state": 1},
+}
+
+func Test_keaAPISource_Leases(t *testing.T) {
+	lease1 := Lease{
+		Mac:   "ac:1f:6b:35:ac:62",
+		Ip:    "10.0.0.1",
+		Begin: time.Date(2080, 12, 16, 12, 20, 0, 0, time.UTC),
+		End:   time.Date(2080, 12, 16, 13, 20, 0, 0, time.UTC),
+	}
+	lease2 := Lease{
+		Mac:   "ac:1f:6b:35:ab:2d",
+		Ip:    "10.0.0.2",
+		Begin: time.Date(2080, 12, 16, 12, 20, 0, 0, time.UTC),
+		End:   time.Date(2080, 12, 16, 13, 20, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name         string
+		cfg          KeaAPIConfig
+		want         Leases
+		wantErr      error
+		wantCommands []string
+	}{
+		{
+			name:         "all leases",
+			cfg:          KeaAPIConfig{User: "kea", Password: "secret"},
+			want:         Leases{lease1, lease2},
+			wantCommands: []string{"lease4-get-all"},
+		},
+		{
+			name:         "all leases of a subnet",
+			cfg:          KeaAPIConfig{User: "kea", Password: "secret", Subnets: []int64{2}},
+			want:         Leases{lease2},
+			wantCommands: []string{"lease4-get-all"},
+		},
+		{
+			name:         "pages of a subnet",
+			cfg:          KeaAPIConfig{User: "kea", Password: "secret", Subnets: []int64{1}, PageSize: 2},
+			want:         Leases{lease1},
+			wantCommands: []string{"lease4-get-page", "lease4-get-page"},
+		},
+		{
+			name:         "wrong password",
+			cfg:          KeaAPIConfig{User: "kea", Password: "wrong"},
+			wantErr:      fmt.Errorf("kea command lease4-get-all failed with status 401 Unauthorized"),
+			wantCommands: []string{"lease4-get-all"},
+		},
+		{
+			name:    "timeout",
+			cfg:     KeaAPIConfig{User: "kea", Password: "secret", Timeout: 10 * time.Millisecond, Subnets: []int64{42}},
+			wantErr: fmt.Errorf("kea command lease4-get-all failed"),
+			// the handler is still blocked when the request timed out, its commands are not checked
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				commands []string
+				unblock  = make(chan struct{})
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var cmd struct {
+					Command   string   `json:"command"`
+					Service   []string `json:"service"`
+					Arguments struct {
+						Subnets []int64 `json:"subnets"`
+						From    string  `json:"from"`
+						Limit   int     `json:"limit"`
+					} `json:"arguments"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&cmd))
+				commands = append(commands, cmd.Command)
+				assert.Equal(t, []string{"dhcp4"}, cmd.Service)
+
+				user, password, ok := r.BasicAuth()
+				if !ok || user != "kea" || password != "secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				var result []map[string]any
+				switch cmd.Command {
+				case keaCommandGetAll:
+					if len(cmd.Arguments.Subnets) > 0 && cmd.Arguments.Subnets[0] == 42 {
+						<-unblock
+					}
+					for _, l := range keaAPILeases {
+						if len(cmd.Arguments.Subnets) == 0 || int64(l["subnet-id"].(int)) == cmd.Arguments.Subnets[0] {
+							result = append(result, l)
+						}
+					}
+				case keaCommandGetPage:
+					start := 0
+					if cmd.Arguments.From != "start" {
+						for i, l := range keaAPILeases {
+							if l["ip-address"] == cmd.Arguments.From {
+								start = i + 1
+							}
+						}
+					}
+					result = keaAPILeases[start:min(start+cmd.Arguments.Limit, len(keaAPILeases))]
+				}
+
+				resp := map[string]any{"result": keaResultSuccess, "arguments": map[string]any{"leases": result}}
+				if len(result) == 0 {
+					resp = map[string]any{"result": keaResultEmpty, "text": "0 IPv4 lease(s) found."}
+				}
+				_ = json.NewEncoder(w).Encode([]any{resp})
+			}))
+			tt.cfg.URL = server.URL
+			got, err := NewKeaAPISource(slog.Default(), tt.cfg).Leases()
+
+			// the handlers finished once the server is closed
+			close(unblock)
+			server.Close()
+			if tt.wantCommands != nil {
+				assert.Equal(t, tt.wantCommands, commands)
+			}
+			if tt.wantErr != nil {
+				require.ErrorContains(t, err, tt.wantErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_keaAPISource_commandFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a dhcp server answers with a single response
+		_, _ = w.Write([]byte(`{"result": 2, "text": "unable to forward command to the dhcp4 service"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewKeaAPISource(slog.Default(), KeaAPIConfig{URL: server.URL}).Leases()
+	require.EqualError(t, err, "kea command lease4-get-all failed with result 2: unable to forward command to the dhcp4 service")
+}
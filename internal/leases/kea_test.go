@@ -0,0 +1,127 @@
+package leases
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+const keaHeader = "address,hwaddr,client_id,valid_lifetime,expire,subnet_id,fqdn_fwd,fqdn_rev,hostname,state,user_context,pool_id\n"
+
+func Test_parseKeaMemfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Leases
+		wantErr error
+	}{
+		{
+			name: "real example",
+			data: keaHeader +
+				"10.0.0.1,ac:1f:6b:35:ac:62,01:ac:1f:6b:35:ac:62,3600,3501577200,1,0,0,,0,,0\n" +
+				"10.0.0.2,AC:1F:6B:35:AB:2D,,3600,3501577200,1,0,0,bmc-2,0,{ \"foo\": 1 },0\n" +
+				// the lease was renewed
+				"10.0.0.1,ac:1f:6b:35:ac:62,01:ac:1f:6b:35:ac:62,3600,3501580800,1,0,0,,0,,0\n" +
+				// the lease was declined
+				"10.0.0.3,ac:1f:6b:35:ab:2e,,3600,3501577200,1,0,0,,0,,0\n" +
+				"10.0.0.3,,,3600,3501577200,1,0,0,,1,,0\n",
+			want: Leases{
+				{
+					Mac:   "ac:1f:6b:35:ac:62",
+					Ip:    "10.0.0.1",
+					Begin: time.Date(2080, 12, 16, 12, 20, 0, 0, time.UTC),
+					End:   time.Date(2080, 12, 16, 13, 20, 0, 0, time.UTC),
+				},
+				{
+					Mac:   "ac:1f:6b:35:ab:2d",
+					Ip:    "10.0.0.2",
+					Begin: time.Date(2080, 12, 16, 11, 20, 0, 0, time.UTC),
+					End:   time.Date(2080, 12, 16, 12, 20, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name:    "missing header",
+			data:    "10.0.0.1,ac:1f:6b:35:ac:62,,3600,3501577200,1,0,0,,0,,0\n",
+			wantErr: fmt.Errorf("missing header before line 1: 10.0.0.1,ac:1f:6b:35:ac:62,,3600,3501577200,1,0,0,,0,,0"),
+		},
+		{
+			name:    "missing column",
+			data:    "address,hwaddr,client_id,valid_lifetime\n",
+			wantErr: fmt.Errorf(`missing column "expire" in header on line 1: address,hwaddr,client_id,valid_lifetime`),
+		},
+		{
+			name:    "too few columns",
+			data:    keaHeader + "10.0.0.1,ac:1f:6b:35:ac:62\n",
+			wantErr: fmt.Errorf("expecting 12 columns on line 2, got: 10.0.0.1,ac:1f:6b:35:ac:62"),
+		},
+		{
+			name: "truncated last line is skipped",
+			data: keaHeader +
+				"10.0.0.1,ac:1f:6b:35:ac:62,01:ac:1f:6b:35:ac:62,3600,3501577200,1,0,0,,0,,0\n" +
+				"10.0.0.2,ac:1f:6b:35:ab:2d,,3600,35015",
+			want: Leases{
+				{
+					Mac:   "ac:1f:6b:35:ac:62",
+					Ip:    "10.0.0.1",
+					Begin: time.Date(2080, 12, 16, 11, 20, 0, 0, time.UTC),
+					End:   time.Date(2080, 12, 16, 12, 20, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "truncated line in the middle",
+			data: keaHeader +
+				"10.0.0.2,ac:1f:6b:35:ab:2d,,3600,35015\n" +
+				"10.0.0.1,ac:1f:6b:35:ac:62,01:ac:1f:6b:35:ac:62,3600,3501577200,1,0,0,,0,,0\n",
+			wantErr: fmt.Errorf("expecting 12 columns on line 2, got: 10.0.0.2,ac:1f:6b:35:ab:2d,,3600,35015"),
+		},
+		{
+			name:    "invalid expire",
+			data:    keaHeader + "10.0.0.1,ac:1f:6b:35:ac:62,,3600,tomorrow,1,0,0,,0,,0\n",
+			wantErr: fmt.Errorf(`invalid expire on line 2: strconv.ParseInt: parsing "tomorrow": invalid syntax`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKeaMemfile(slog.Default(), tt.data)
+			if tt.wantErr != nil {
+				require.EqualError(t, err, tt.wantErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_keaMemfileSource_Leases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kea-leases4.csv")
+
+	// a lease file cleanup is running, the previous file contains an older state of the lease
+	require.NoError(t, os.WriteFile(path+keaPreviousSuffix, []byte(keaHeader+"10.0.0.1,ac:1f:6b:35:ac:62,,3600,3501577200,1,0,0,,0,,0\n"), 0600))
+	require.NoError(t, os.WriteFile(path, []byte(keaHeader+"10.0.0.1,ac:1f:6b:35:ac:62,,3600,3501580800,1,0,0,,0,,0\n"), 0600))
+
+	got, err := NewKeaMemfileSource(slog.Default(), path).Leases()
+	require.NoError(t, err)
+
+	want := Leases{
+		{
+			Mac:   "ac:1f:6b:35:ac:62",
+			Ip:    "10.0.0.1",
+			Begin: time.Date(2080, 12, 16, 12, 20, 0, 0, time.UTC),
+			End:   time.Date(2080, 12, 16, 13, 20, 0, 0, time.UTC),
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
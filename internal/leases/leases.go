@@ -3,10 +3,23 @@ package leases
 import (
 	"fmt"
 	"log/slog"
+	"net/netip"
 	"os"
 	"time"
 )
 
+// AddrPort returns the leased ip with the given port, the port of the lease takes precedence if set.
+func (l Lease) AddrPort(port int) (netip.AddrPort, error) {
+	ip, err := netip.ParseAddr(l.Ip)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	if l.Port != 0 {
+		port = l.Port
+	}
+	return netip.AddrPortFrom(ip, uint16(port)), nil // nolint:gosec
+}
+
 func (l Leases) FilterActive() Leases {
 	active := Leases{}
 	now := time.Now()
@@ -37,7 +50,12 @@ func ReadLeases(log *slog.Logger, leaseFilePath string) (Leases, error) {
 		return nil, err
 	}
 
-	leases, err := parseLeasesFile(log, string(data))
+	parse := parseLeasesFile
+	if isLeases6File(string(data)) {
+		parse = parseLeases6File
+	}
+
+	leases, err := parse(log, string(data))
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse lease file: %w", err)
 	}
@@ -71,19 +71,9 @@ func parseLeasesFile(log *slog.Logger, data string) (Leases, error) {
 				return nil, fmt.Errorf("unexpected date field on line %d: %s", i+1, line)
 			}
 
-			if len(tokens) != 4 {
-				return nil, fmt.Errorf(`expecting "%s <whatever-number> <date> <time>;" on line %d, got: %s`, tokens[0], i+1, line)
-			}
-
-			if !strings.HasSuffix(tokens[3], ";") {
-				return nil, fmt.Errorf("missing semicolon on line %d: %s", i+1, line)
-			}
-
-			tokens[3] = strings.TrimRight(tokens[3], ";")
-
-			t, err := time.Parse(leaseDateFormat, tokens[2]+" "+tokens[3])
+			t, err := parseLeaseDate(tokens, i, line)
 			if err != nil {
-				return nil, fmt.Errorf("invalid time format on line %d: %w", i+1, err)
+				return nil, err
 			}
 
 			if tokens[0] == "starts" {
@@ -120,3 +110,25 @@ func parseLeasesFile(log *slog.Logger, data string) (Leases, error) {
 
 	return leases, nil
 }
+
+// parseLeaseDate parses date fields like "starts 5 2026/01/09 12:35:39;" of line number i, "ends never;" is returned as infinite lease end.
+func parseLeaseDate(tokens []string, i int, line string) (time.Time, error) {
+	if len(tokens) == 2 && tokens[1] == "never;" {
+		return infiniteLeaseEnd, nil
+	}
+
+	if len(tokens) != 4 {
+		return time.Time{}, fmt.Errorf(`expecting "%s <whatever-number> <date> <time>;" on line %d, got: %s`, tokens[0], i+1, line)
+	}
+
+	if !strings.HasSuffix(tokens[3], ";") {
+		return time.Time{}, fmt.Errorf("missing semicolon on line %d: %s", i+1, line)
+	}
+
+	t, err := time.Parse(leaseDateFormat, tokens[2]+" "+strings.TrimRight(tokens[3], ";"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time format on line %d: %w", i+1, err)
+	}
+
+	return t, nil
+}
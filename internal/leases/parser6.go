@@ -0,0 +1,301 @@
+package leases
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// duid types as defined in rfc 8415
+	duidTypeLLT = 1
+	duidTypeLL  = 3
+
+	// hardware type ethernet as defined in rfc 826
+	hardwareTypeEthernet = 1
+
+	// length of the iaid which is prepended to the duid in the lease file
+	iaidLength = 4
+)
+
+// isLeases6File returns true if the given lease file was written by dhcpd in dhcpv6 mode.
+func isLeases6File(data string) bool {
+	for line := range strings.SplitSeq(data, "\n") {
+		tokens := strings.Fields(line)
+		if len(tokens) == 0 {
+			continue
+		}
+		switch tokens[0] {
+		case "ia-na", "ia-ta", "ia-pd", "server-duid":
+			return true
+		case "lease":
+			return false
+		}
+	}
+	return false
+}
+
+// parseLeases6File parses a dhcpv6 lease file, only active non-temporary addresses (ia-na) are considered.
+// The mac address is taken from the link-layer address of the client duid, leases of clients
+// with a duid which does not contain a link-layer address are skipped.
+func parseLeases6File(log *slog.Logger, data string) (Leases, error) {
+	var (
+		leases Leases
+		// depth of nested blocks
+		depth int
+		// skip is the depth of nested blocks inside of an ignored block
+		skip int
+		// ia is set while inside of an ia-na block
+		ia *identityAssociation
+		// current is set while inside of an iaaddr block of an ia-na block
+		current *Lease
+		// state is the binding state of the current lease
+		state string
+	)
+
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+
+		tokens := strings.Fields(line)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		if skip > 0 {
+			switch {
+			case strings.HasSuffix(line, "{"):
+				skip++
+			case tokens[0] == "}":
+				skip--
+			}
+			continue
+		}
+
+		switch tokens[0] {
+		case "ia-na":
+			// ia-na "\001\000\000\000\000\003\000\001\254\037k5\254b" {
+			if depth != 0 {
+				return nil, fmt.Errorf("unexpected ia-na block on line %d: %s", i+1, line)
+			}
+
+			// the quoted iaid and duid may contain spaces
+			rest, found := strings.CutSuffix(strings.TrimSpace(strings.TrimPrefix(line, "ia-na")), "{")
+			rest = strings.TrimSpace(rest)
+			if !found || rest == "" {
+				return nil, fmt.Errorf(`expecting "ia-na <iaid-duid> {" on line %d, got: %s`, i+1, line)
+			}
+
+			id, err := parseLeaseString(rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid iaid and duid on line %d: %w", i+1, err)
+			}
+
+			if len(id) <= iaidLength {
+				return nil, fmt.Errorf("iaid and duid too short on line %d: %s", i+1, line)
+			}
+
+			ia = &identityAssociation{
+				duid: id[iaidLength:],
+			}
+			depth++
+
+		case "iaaddr":
+			// iaaddr fd00::10 {
+			if ia == nil || depth != 1 {
+				return nil, fmt.Errorf("unexpected iaaddr block on line %d: %s", i+1, line)
+			}
+
+			if len(tokens) != 3 || tokens[2] != "{" {
+				return nil, fmt.Errorf(`expecting "iaaddr <ip> {" on line %d, got: %s`, i+1, line)
+			}
+
+			if _, err := netip.ParseAddr(tokens[1]); err != nil {
+				return nil, fmt.Errorf("invalid ip address on line %d: %w", i+1, err)
+			}
+
+			current = &Lease{
+				Ip: tokens[1],
+			}
+			state = ""
+			depth++
+
+		case "}":
+			if depth == 0 {
+				return nil, fmt.Errorf("unexpected closing brace on line %d: %s", i+1, line)
+			}
+			depth--
+
+			switch {
+			case current != nil:
+				lease := *current
+				current = nil
+
+				mac, err := ia.mac()
+				if err != nil {
+					log.Warn("unable to determine mac address from duid, skipping entry", "line", i+1, "duid", ia.duidString(), "error", err)
+					continue
+				}
+
+				lease.Mac = mac
+				lease.Duid = ia.duidString()
+				lease.Begin = ia.cltt
+
+				if lease.Begin.IsZero() || lease.End.IsZero() {
+					log.Warn("incomplete lease entry (missing cltt and end time), skipping entry", "line", i+1)
+					continue
+				}
+
+				if state != "" && state != "active" {
+					log.Debug("lease is not active, skipping entry", "line", i+1, "ip", lease.Ip, "state", state)
+					continue
+				}
+
+				leases = append(leases, lease)
+			case depth == 0:
+				ia = nil
+			}
+
+		case "cltt":
+			// cltt 4 2019/06/27 13:30:21;
+			if ia == nil || current != nil {
+				continue
+			}
+
+			t, err := parseLeaseDate(tokens, i, line)
+			if err != nil {
+				return nil, err
+			}
+			ia.cltt = t
+
+		case "binding":
+			// binding state active;
+			if current == nil {
+				continue
+			}
+
+			if len(tokens) != 3 || tokens[1] != "state" || !strings.HasSuffix(tokens[2], ";") {
+				return nil, fmt.Errorf(`expecting "binding state <state>;" on line %d, got: %s`, i+1, line)
+			}
+			state = strings.TrimRight(tokens[2], ";")
+
+		case "ends":
+			// ends 4 2019/06/27 13:40:21;
+			// ends never;
+			if current == nil {
+				continue
+			}
+
+			t, err := parseLeaseDate(tokens, i, line)
+			if err != nil {
+				return nil, err
+			}
+			current.End = t
+
+		default:
+			// ia-ta, ia-pd and all other blocks are ignored including their nested blocks
+			if strings.HasSuffix(line, "{") {
+				skip = 1
+			}
+		}
+	}
+
+	if depth != 0 || skip != 0 {
+		return nil, fmt.Errorf("lease entry was not closed")
+	}
+
+	return leases, nil
+}
+
+// identityAssociation of a dhcpv6 client
+type identityAssociation struct {
+	duid []byte
+	cltt time.Time
+}
+
+// mac returns the link-layer address of duids of type LLT and LL.
+func (ia *identityAssociation) mac() (string, error) {
+	if len(ia.duid) < 4 {
+		return "", fmt.Errorf("duid too short")
+	}
+
+	var lladdr []byte
+	switch binary.BigEndian.Uint16(ia.duid[0:2]) {
+	case duidTypeLLT:
+		if len(ia.duid) < 8 {
+			return "", fmt.Errorf("duid too short")
+		}
+		lladdr = ia.duid[8:]
+	case duidTypeLL:
+		lladdr = ia.duid[4:]
+	default:
+		return "", fmt.Errorf("duid of type %d does not contain a link-layer address", binary.BigEndian.Uint16(ia.duid[0:2]))
+	}
+
+	if hwType := binary.BigEndian.Uint16(ia.duid[2:4]); hwType != hardwareTypeEthernet {
+		return "", fmt.Errorf("unsupported hardware type %d", hwType)
+	}
+
+	if len(lladdr) != 6 {
+		return "", fmt.Errorf("invalid ethernet address length %d", len(lladdr))
+	}
+
+	return net.HardwareAddr(lladdr).String(), nil
+}
+
+func (ia *identityAssociation) duidString() string {
+	parts := make([]string, 0, len(ia.duid))
+	for _, b := range ia.duid {
+		parts = append(parts, hex.EncodeToString([]byte{b}))
+	}
+	return strings.Join(parts, ":")
+}
+
+// parseLeaseString parses binary data written by dhcpd, either as quoted string with octal escapes
+// or as colon separated hex bytes.
+func parseLeaseString(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	}
+
+	if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+		return nil, fmt.Errorf("missing closing quote: %s", s)
+	}
+	s = s[1 : len(s)-1]
+
+	var result []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			result = append(result, s[i])
+			continue
+		}
+
+		if i+1 >= len(s) {
+			return nil, fmt.Errorf("invalid escape sequence at end of string")
+		}
+
+		if s[i+1] < '0' || s[i+1] > '7' {
+			result = append(result, s[i+1])
+			i++
+			continue
+		}
+
+		if i+4 > len(s) {
+			return nil, fmt.Errorf("invalid octal escape sequence: %s", s[i:])
+		}
+
+		b, err := strconv.ParseUint(s[i+1:i+4], 8, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid octal escape sequence: %w", err)
+		}
+		result = append(result, byte(b))
+		i += 3
+	}
+
+	return result, nil
+}
@@ -0,0 +1,223 @@
+package leases
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-lib/pkg/testcommon"
+)
+
+var sampleLease6Content = `
+# The format of this file is documented in the dhcpd.leases(5) manual page.
+# This lease file was written by isc-dhcp-4.4.1
+
+# authoring-byte-order entry is generated, DO NOT DELETE
+authoring-byte-order little-endian;
+
+server-duid "\000\001\000\001$^\020\001\000\000\000\000\000\001";
+
+ia-na "k5\254b\000\001\000\001$^\020\001\254\037k5\254b" {
+  cltt 4 2019/06/27 13:30:21;
+  iaaddr fd00::10 {
+    binding state active;
+    preferred-life 375;
+    max-life 600;
+    ends 4 2019/06/27 13:40:21;
+  }
+}
+
+ia-na "k5\254b\000\003\000\001\254\037k5\253-" {
+  cltt 4 2019/06/27 06:40:06;
+  iaaddr fd00::11 {
+    binding state active;
+    preferred-life 375;
+    max-life 600;
+    ends 4 2019/06/27 06:50:06;
+  }
+}
+
+ia-na "k5\254b\000\002\000\000\012L\001\002\003" {
+  cltt 4 2019/06/27 06:40:06;
+  iaaddr fd00::12 {
+    binding state active;
+    preferred-life 375;
+    max-life 600;
+    ends 4 2019/06/27 06:50:06;
+  }
+}
+
+ia-pd "k5\254b\000\003\000\001\254\037k5\253-" {
+  cltt 4 2019/06/27 06:40:06;
+  iaprefix fd00:1::/64 {
+    binding state active;
+    preferred-life 375;
+    max-life 600;
+    ends 4 2019/06/27 06:50:06;
+  }
+}
+`
+
+func Test_parseLeases6File(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Leases
+		wantErr error
+	}{
+		{
+			name: "not closed entry",
+			data: `ia-na "k5\254b\000\003\000\001\254\037k5\253-" {
+				cltt 4 2019/06/27 06:40:06;
+				iaaddr fd00::11 {
+					ends 4 2019/06/27 06:50:06;
+				}
+			`,
+			wantErr: fmt.Errorf("lease entry was not closed"),
+		},
+		{
+			name: "invalid ip address",
+			data: `ia-na "k5\254b\000\003\000\001\254\037k5\253-" {
+				iaaddr fd00::zz {
+				}
+			}`,
+			wantErr: fmt.Errorf("invalid ip address on line 2: %w", fmt.Errorf(`ParseAddr("fd00::zz"): each colon-separated field must have at least one digit (at "zz")`)),
+		},
+		{
+			name: "iaaddr outside of ia-na",
+			data: `iaaddr fd00::11 {
+			}`,
+			wantErr: fmt.Errorf("unexpected iaaddr block on line 1: iaaddr fd00::11 {"),
+		},
+		{
+			name: "hex encoded duid",
+			data: `ia-na 6b:35:ac:62:00:03:00:01:ac:1f:6b:35:ab:2d {
+				cltt 4 2019/06/27 06:40:06;
+				iaaddr fd00::11 {
+					ends 4 2019/06/27 06:50:06;
+				}
+			}`,
+			want: Leases{
+				{
+					Mac:   "ac:1f:6b:35:ab:2d",
+					Ip:    "fd00::11",
+					Duid:  "00:03:00:01:ac:1f:6b:35:ab:2d",
+					Begin: time.Date(2019, 06, 27, 6, 40, 06, 0, time.UTC),
+					End:   time.Date(2019, 06, 27, 6, 50, 06, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "temporary addresses are ignored",
+			data: `ia-ta "k5\254b\000\003\000\001\254\037k5\253-" {
+				cltt 4 2019/06/27 06:40:06;
+				iaaddr fd00::20 {
+					binding state active;
+					ends 4 2019/06/27 06:50:06;
+				}
+			}
+			ia-na "k5\254b\000\003\000\001\254\037k5\253-" {
+				cltt 4 2019/06/27 06:40:06;
+				iaaddr fd00::11 {
+					binding state active;
+					ends 4 2019/06/27 06:50:06;
+				}
+			}`,
+			want: Leases{
+				{
+					Mac:   "ac:1f:6b:35:ab:2d",
+					Ip:    "fd00::11",
+					Duid:  "00:03:00:01:ac:1f:6b:35:ab:2d",
+					Begin: time.Date(2019, 06, 27, 6, 40, 06, 0, time.UTC),
+					End:   time.Date(2019, 06, 27, 6, 50, 06, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "lease which never ends",
+			data: `ia-na "k5\254b\000\003\000\001\254\037k5\253-" {
+				cltt 4 2019/06/27 06:40:06;
+				iaaddr fd00::11 {
+					binding state active;
+					ends never;
+				}
+			}`,
+			want: Leases{
+				{
+					Mac:   "ac:1f:6b:35:ab:2d",
+					Ip:    "fd00::11",
+					Duid:  "00:03:00:01:ac:1f:6b:35:ab:2d",
+					Begin: time.Date(2019, 06, 27, 6, 40, 06, 0, time.UTC),
+					End:   infiniteLeaseEnd,
+				},
+			},
+		},
+		{
+			name: "inactive leases are skipped",
+			data: `ia-na "k5\254b\000\003\000\001\254\037k5\253-" {
+				cltt 4 2019/06/27 06:40:06;
+				iaaddr fd00::11 {
+					binding state expired;
+					ends 4 2019/06/27 06:50:06;
+				}
+				iaaddr fd00::12 {
+					binding state released;
+					ends 4 2019/06/27 06:50:06;
+				}
+			}`,
+		},
+		{
+			name: "invalid binding state",
+			data: `ia-na "k5\254b\000\003\000\001\254\037k5\253-" {
+				iaaddr fd00::11 {
+					binding active;
+				}
+			}`,
+			wantErr: fmt.Errorf(`expecting "binding state <state>;" on line 3, got: binding active;`),
+		},
+		{
+			name: "real example",
+			data: sampleLease6Content,
+			want: Leases{
+				{
+					Mac:   "ac:1f:6b:35:ac:62",
+					Ip:    "fd00::10",
+					Duid:  "00:01:00:01:24:5e:10:01:ac:1f:6b:35:ac:62",
+					Begin: time.Date(2019, 06, 27, 13, 30, 21, 0, time.UTC),
+					End:   time.Date(2019, 06, 27, 13, 40, 21, 0, time.UTC),
+				},
+				{
+					Mac:   "ac:1f:6b:35:ab:2d",
+					Ip:    "fd00::11",
+					Duid:  "00:03:00:01:ac:1f:6b:35:ab:2d",
+					Begin: time.Date(2019, 06, 27, 6, 40, 06, 0, time.UTC),
+					End:   time.Date(2019, 06, 27, 6, 50, 06, 0, time.UTC),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := parseLeases6File(slog.Default(), tt.data)
+			if diff := cmp.Diff(tt.wantErr, gotErr, testcommon.ErrorStringComparer()); diff != "" {
+				t.Errorf("error diff = %s", diff)
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_isLeases6File(t *testing.T) {
+	if !isLeases6File(sampleLease6Content) {
+		t.Errorf("expected dhcpv6 lease file to be detected")
+	}
+	if isLeases6File(sampleLeaseContent) {
+		t.Errorf("expected dhcpv4 lease file not to be detected as dhcpv6")
+	}
+}
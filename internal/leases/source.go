@@ -0,0 +1,35 @@
+package leases
+
+import (
+	"log/slog"
+)
+
+const (
+	// SourceISC reads the lease file of isc dhcpd
+	SourceISC = "isc"
+	// SourceKeaMemfile reads the csv lease file of the memfile backend of kea
+	SourceKeaMemfile = "kea-memfile"
+	// SourceKeaAPI reads the leases from the json api of the kea control agent
+	SourceKeaAPI = "kea-api"
+	// SourceDnsmasq reads the lease file of dnsmasq
+	SourceDnsmasq = "dnsmasq"
+)
+
+// LeaseSource provides the leases of the bmcs
+type LeaseSource interface {
+	Leases() (Leases, error)
+}
+
+type iscSource struct {
+	log  *slog.Logger
+	path string
+}
+
+// NewISCSource returns a lease source which reads the dhcpv4 or dhcpv6 lease file of isc dhcpd.
+func NewISCSource(log *slog.Logger, path string) LeaseSource {
+	return &iscSource{log: log, path: path}
+}
+
+func (s *iscSource) Leases() (Leases, error) {
+	return ReadLeases(s.log, s.path)
+}
@@ -0,0 +1,217 @@
+package leases
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// StaticDevice is a bmc with a static address which is not leased by a dhcp server
+type StaticDevice struct {
+	Mac string `json:"mac" yaml:"mac"`
+	Ip  string `json:"ip" yaml:"ip"`
+	// Port is the ipmi port of the bmc, defaults to the configured ipmi port
+	Port int `json:"port,omitempty" yaml:"port,omitempty"`
+	// User and Password are tried before all credential sets if given
+	User     string `json:"user,omitempty" yaml:"user,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+}
+
+// StaticInventory contains the bmcs of a static inventory file, the file is reloaded when it changed.
+type StaticInventory struct {
+	log  *slog.Logger
+	path string
+
+	lock    sync.RWMutex
+	devices map[string]StaticDevice
+	modTime time.Time
+	size    int64
+}
+
+// NewStaticInventory loads the static inventory from the given yaml or json file.
+func NewStaticInventory(log *slog.Logger, path string) (*StaticInventory, error) {
+	s := &StaticInventory{
+		log:  log,
+		path: path,
+	}
+	err := s.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the inventory file again if it changed since it was read last.
+// If the file is invalid the previous devices are kept.
+func (s *StaticInventory) Reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.lock.RLock()
+	unchanged := s.devices != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size
+	s.lock.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	devices, err := parseStaticInventory(data)
+	if err != nil {
+		return fmt.Errorf("unable to parse static inventory file: %w", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.devices = devices
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+
+	s.log.Info("loaded static inventory", "file", s.path, "devices", len(devices))
+	return nil
+}
+
+func parseStaticInventory(data []byte) (map[string]StaticDevice, error) {
+	var list []StaticDevice
+	err := yaml.Unmarshal(data, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make(map[string]StaticDevice)
+	ips := make(map[netip.Addr]bool)
+	for i, d := range list {
+		mac, err := net.ParseMAC(d.Mac)
+		if err != nil {
+			return nil, fmt.Errorf("device %d has an invalid mac address: %w", i, err)
+		}
+		d.Mac = mac.String()
+		if _, ok := devices[d.Mac]; ok {
+			return nil, fmt.Errorf("device %d has a duplicate mac address %s", i, d.Mac)
+		}
+
+		ip, err := netip.ParseAddr(d.Ip)
+		if err != nil {
+			return nil, fmt.Errorf("device %d has an invalid ip address: %w", i, err)
+		}
+		if ips[ip] {
+			return nil, fmt.Errorf("device %d has a duplicate ip address %s", i, d.Ip)
+		}
+		ips[ip] = true
+
+		if d.Port < 0 || d.Port > 65535 {
+			return nil, fmt.Errorf("device %d has an invalid port %d", i, d.Port)
+		}
+		if d.User == "" && d.Password != "" {
+			return nil, fmt.Errorf("device %d has a password but no user", i)
+		}
+
+		devices[d.Mac] = d
+	}
+	return devices, nil
+}
+
+// Device returns the static device with the given mac.
+func (s *StaticInventory) Device(mac string) (StaticDevice, bool) {
+	if s == nil {
+		return StaticDevice{}, false
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	d, ok := s.devices[strings.ToLower(mac)]
+	return d, ok
+}
+
+// Leases returns a lease which never expires for every static device, sorted by mac.
+func (s *StaticInventory) Leases() Leases {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var leases Leases
+	for _, d := range s.devices {
+		leases = append(leases, Lease{
+			Mac:  d.Mac,
+			Ip:   d.Ip,
+			Port: d.Port,
+			End:  infiniteLeaseEnd,
+		})
+	}
+	slices.SortFunc(leases, func(a, b Lease) int {
+		return strings.Compare(a.Mac, b.Mac)
+	})
+	return leases
+}
+
+type mergedSource struct {
+	log    *slog.Logger
+	dhcp   LeaseSource
+	static *StaticInventory
+
+	lock sync.Mutex
+	// lastDHCP are the dhcp leases which were read last
+	lastDHCP Leases
+}
+
+// NewMergedSource returns a lease source which merges the dhcp leases with the devices of the static inventory.
+// A static device takes precedence over dhcp leases with the same mac or ip.
+// If the dhcp leases can not be read the previous ones are kept, an error is only returned if the static inventory fails as well.
+func NewMergedSource(log *slog.Logger, dhcp LeaseSource, static *StaticInventory) LeaseSource {
+	return &mergedSource{log: log, dhcp: dhcp, static: static}
+}
+
+func (m *mergedSource) Leases() (Leases, error) {
+	ls, dhcpErr := m.dhcp.Leases()
+	staticErr := m.static.Reload()
+	if dhcpErr != nil && staticErr != nil {
+		return nil, fmt.Errorf("unable to read dhcp leases: %w, unable to reload static inventory: %w", dhcpErr, staticErr)
+	}
+
+	m.lock.Lock()
+	if dhcpErr != nil {
+		m.log.Error("unable to read dhcp leases, keeping the previous leases", "error", dhcpErr)
+		ls = m.lastDHCP
+	} else {
+		m.lastDHCP = ls
+	}
+	m.lock.Unlock()
+
+	if staticErr != nil {
+		m.log.Error("unable to reload static inventory, keeping the previous devices", "error", staticErr)
+	}
+	static := m.static.Leases()
+
+	macs := make(map[string]bool)
+	ips := make(map[netip.Addr]string)
+	for _, l := range static {
+		macs[l.Mac] = true
+		ips[netip.MustParseAddr(l.Ip)] = l.Mac
+	}
+
+	var merged Leases
+	for _, l := range ls {
+		if macs[strings.ToLower(l.Mac)] {
+			continue
+		}
+		if ip, err := netip.ParseAddr(l.Ip); err == nil {
+			if mac, ok := ips[ip]; ok {
+				m.log.Warn("dhcp lease uses the ip of a static device, skipping entry", "mac", l.Mac, "ip", l.Ip, "static mac", mac)
+				continue
+			}
+		}
+		merged = append(merged, l)
+	}
+
+	return append(merged, static...), nil
+}
@@ -0,0 +1,175 @@
+package leases
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseStaticInventory(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]StaticDevice
+		wantErr error
+	}{
+		{
+			name: "yaml",
+			data: `
+- mac: AC:1F:6B:35:AC:62
+  ip: 10.0.0.1
+  port: 6230
+  user: root
+  password: secret
+- mac: ac:1f:6b:35:ac:63
+  ip: 10.0.0.2
+`,
+			want: map[string]StaticDevice{
+				"ac:1f:6b:35:ac:62": {Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1", Port: 6230, User: "root", Password: "secret"},
+				"ac:1f:6b:35:ac:63": {Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.2"},
+			},
+		},
+		{
+			name: "json",
+			data: `[{"mac": "ac:1f:6b:35:ac:62", "ip": "10.0.0.1"}]`,
+			want: map[string]StaticDevice{
+				"ac:1f:6b:35:ac:62": {Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"},
+			},
+		},
+		{
+			name:    "duplicate mac",
+			data:    `[{"mac": "ac:1f:6b:35:ac:62", "ip": "10.0.0.1"}, {"mac": "AC:1F:6B:35:AC:62", "ip": "10.0.0.2"}]`,
+			wantErr: fmt.Errorf("device 1 has a duplicate mac address ac:1f:6b:35:ac:62"),
+		},
+		{
+			name:    "duplicate ip",
+			data:    `[{"mac": "ac:1f:6b:35:ac:62", "ip": "10.0.0.1"}, {"mac": "ac:1f:6b:35:ac:63", "ip": "10.0.0.1"}]`,
+			wantErr: fmt.Errorf("device 1 has a duplicate ip address 10.0.0.1"),
+		},
+		{
+			name:    "invalid port",
+			data:    `[{"mac": "ac:1f:6b:35:ac:62", "ip": "10.0.0.1", "port": 70000}]`,
+			wantErr: fmt.Errorf("device 0 has an invalid port 70000"),
+		},
+		{
+			name:    "password without user",
+			data:    `[{"mac": "ac:1f:6b:35:ac:62", "ip": "10.0.0.1", "password": "secret"}]`,
+			wantErr: fmt.Errorf("device 0 has a password but no user"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStaticInventory([]byte(tt.data))
+			if tt.wantErr != nil {
+				require.EqualError(t, err, tt.wantErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
+
+type testSource struct {
+	leases Leases
+	err    error
+}
+
+func (s *testSource) Leases() (Leases, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.leases, nil
+}
+
+func Test_mergedSource_Leases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"mac": "ac:1f:6b:35:ac:62", "ip": "10.0.0.1", "port": 6230, "user": "root", "password": "secret"}]`), 0600))
+
+	static, err := NewStaticInventory(slog.Default(), path)
+	require.NoError(t, err)
+
+	end := time.Date(2080, 12, 16, 12, 20, 0, 0, time.UTC)
+	dhcp := &testSource{leases: Leases{
+		// the static device takes precedence over leases with the same mac or ip
+		{Mac: "AC:1F:6B:35:AC:62", Ip: "10.0.0.5", End: end},
+		{Mac: "ac:1f:6b:35:ac:64", Ip: "10.0.0.1", End: end},
+		{Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.2", End: end},
+	}}
+	source := NewMergedSource(slog.Default(), dhcp, static)
+
+	got, err := source.Leases()
+	require.NoError(t, err)
+	want := Leases{
+		{Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.2", End: end},
+		{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1", Port: 6230, End: infiniteLeaseEnd},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	d, ok := static.Device("AC:1F:6B:35:AC:62")
+	require.True(t, ok)
+	assert.Equal(t, "root", d.User)
+
+	// an invalid file keeps the previous devices
+	require.NoError(t, os.WriteFile(path, []byte(`[{"mac": "invalid"}]`), 0600))
+	got, err = source.Leases()
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	// a changed file is reloaded
+	require.NoError(t, os.WriteFile(path, []byte(`[{"mac": "ac:1f:6b:35:ac:65", "ip": "10.0.0.3"}]`), 0600))
+	got, err = source.Leases()
+	require.NoError(t, err)
+	want = Leases{
+		{Mac: "AC:1F:6B:35:AC:62", Ip: "10.0.0.5", End: end},
+		{Mac: "ac:1f:6b:35:ac:64", Ip: "10.0.0.1", End: end},
+		{Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.2", End: end},
+		{Mac: "ac:1f:6b:35:ac:65", Ip: "10.0.0.3", End: infiniteLeaseEnd},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	// failing dhcp leases keep the previous ones
+	dhcp.err = fmt.Errorf("kea is unavailable")
+	got, err = source.Leases()
+	require.NoError(t, err)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	// an error is only returned if the static inventory fails as well
+	require.NoError(t, os.Remove(path))
+	_, err = source.Leases()
+	require.Error(t, err)
+}
+
+func Test_mergedSource_Leases_failingDHCP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"mac": "ac:1f:6b:35:ac:62", "ip": "10.0.0.1"}]`), 0600))
+
+	static, err := NewStaticInventory(slog.Default(), path)
+	require.NoError(t, err)
+
+	// the static devices are reported even if the dhcp leases were never read
+	source := NewMergedSource(slog.Default(), &testSource{err: fmt.Errorf("kea is unavailable")}, static)
+	got, err := source.Leases()
+	require.NoError(t, err)
+	want := Leases{
+		{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1", End: infiniteLeaseEnd},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
@@ -1,14 +1,24 @@
 package leases
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/metal-stack/metal-bmc/internal/redfish"
 	"github.com/metal-stack/metal-go/api/models"
 )
 
+// infiniteLeaseEnd is the end of leases which never expire
+var infiniteLeaseEnd = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
 type Lease struct {
-	Mac   string
-	Ip    string
+	Mac string
+	Ip  string
+	// Duid of the client, only set for dhcpv6 leases
+	Duid string
+	// Port of the bmc, only set for static devices with a port
+	Port int
+	// Begin is not set for dnsmasq leases
 	Begin time.Time
 	End   time.Time
 }
@@ -25,4 +35,43 @@ type ReportItem struct {
 	IndicatorLED  *string
 	PowerMetric   *models.V1PowerMetric
 	PowerSupplies []*models.V1PowerSupply
+	// Sensors are only read if the collection of sensors is enabled
+	Sensors []redfish.Sensor
+	// LogEntries which were added since the last cycle, only read if the collection of logs is enabled
+	LogEntries []redfish.LogEntry
+	// LogAlerts are the warning and critical log entries which were not acknowledged yet, only read if the collection of logs is enabled
+	LogAlerts []redfish.LogEntry
+	// Errors of optional stages which occurred during enrichment
+	Errors []*EnrichmentError
+}
+
+// EnrichmentStage is a stage of reading the details of a bmc
+type EnrichmentStage string
+
+const (
+	StageConnect    EnrichmentStage = "connect"
+	StageBMCDetails EnrichmentStage = "bmc-details"
+	StagePowerState EnrichmentStage = "power-state"
+	StageUUID       EnrichmentStage = "uuid"
+	StageSensors    EnrichmentStage = "sensors"
+	StageLogs       EnrichmentStage = "logs"
+	StageInventory  EnrichmentStage = "inventory"
+	// StageProvisioning is the creation of a unique bmc user after the details were read
+	StageProvisioning EnrichmentStage = "provisioning"
+	// StageRotation is the rotation of the password of a provisioned bmc user
+	StageRotation EnrichmentStage = "rotation"
+)
+
+// EnrichmentError is returned if reading the details of a bmc failed in a certain stage
+type EnrichmentError struct {
+	Stage EnrichmentStage
+	Err   error
+}
+
+func (e *EnrichmentError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Stage, e.Err)
+}
+
+func (e *EnrichmentError) Unwrap() error {
+	return e.Err
 }
@@ -0,0 +1,234 @@
+package redfish
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/stmcginnis/gofish/schemas"
+)
+
+// ComponentKind is the kind of a hardware component
+type ComponentKind string
+
+const (
+	ComponentCPU               ComponentKind = "cpu"
+	ComponentDIMM              ComponentKind = "dimm"
+	ComponentDrive             ComponentKind = "drive"
+	ComponentStorageController ComponentKind = "storage-controller"
+	ComponentNIC               ComponentKind = "nic"
+	ComponentPCIeDevice        ComponentKind = "pcie-device"
+)
+
+// Component is a hardware component of a machine, it is identified by its system, kind and slot
+type Component struct {
+	// System is the id of the computer system the component belongs to, slots are only unique within a system
+	System string        `json:"system"`
+	Kind   ComponentKind `json:"kind"`
+	// Slot is the location of the component, e.g. the cpu socket or the dimm locator
+	Slot            string `json:"slot"`
+	Manufacturer    string `json:"manufacturer,omitempty"`
+	Model           string `json:"model,omitempty"`
+	PartNumber      string `json:"part_number,omitempty"`
+	SerialNumber    string `json:"serial_number,omitempty"`
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+	CapacityBytes   int64  `json:"capacity_bytes,omitempty"`
+	Cores           int    `json:"cores,omitempty"`
+	MACAddress      string `json:"mac_address,omitempty"`
+}
+
+// Key identifies the component within the inventory of a machine.
+func (c Component) Key() string {
+	return c.System + "/" + string(c.Kind) + "/" + c.Slot
+}
+
+// SameHardware returns true if both components are the same piece of hardware, the firmware is not considered.
+func (c Component) SameHardware(o Component) bool {
+	if c.SerialNumber != "" || o.SerialNumber != "" {
+		return c.SerialNumber == o.SerialNumber
+	}
+	return c.Manufacturer == o.Manufacturer && c.Model == o.Model && c.PartNumber == o.PartNumber && c.MACAddress == o.MACAddress
+}
+
+// Inventory is the normalized hardware inventory of a machine, the components are sorted by their key
+type Inventory struct {
+	Components []Component `json:"components"`
+	// Failed are the kinds of components which could not be read, the inventory lacks components of these kinds
+	Failed []ComponentKind `json:"failed,omitempty"`
+}
+
+// HasFailed returns true if the components of the given kind could not be read.
+func (i *Inventory) HasFailed(kind ComponentKind) bool {
+	return slices.Contains(i.Failed, kind)
+}
+
+// Inventory reads the cpus, dimms, drives, storage controllers, nics and pcie devices of all systems of the bmc.
+// Components which are reported as absent are omitted. The kinds of components which could not be read are returned as failed,
+// an error is only returned if no component could be read.
+func (c *Client) Inventory() (*Inventory, error) {
+	service, cancel, err := c.service()
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	systems, err := service.Systems()
+	if err != nil {
+		return nil, err
+	}
+	if len(systems) == 0 {
+		return nil, fmt.Errorf("bmc does not report any system")
+	}
+
+	var (
+		inventory = &Inventory{}
+		errs      []error
+	)
+	fail := func(err error, kinds ...ComponentKind) {
+		errs = append(errs, err)
+		for _, kind := range kinds {
+			if !inventory.HasFailed(kind) {
+				inventory.Failed = append(inventory.Failed, kind)
+			}
+		}
+	}
+	add := func(system string, kind ComponentKind, status schemas.Status, component Component) {
+		if status.State == schemas.AbsentState {
+			return
+		}
+		component.System = system
+		component.Kind = kind
+		inventory.Components = append(inventory.Components, component)
+	}
+
+	for _, system := range systems {
+		processors, err := system.Processors()
+		if err != nil {
+			fail(fmt.Errorf("unable to read processors of system %s: %w", system.ID, err), ComponentCPU)
+		}
+		for _, p := range processors {
+			add(system.ID, ComponentCPU, p.Status, Component{
+				Slot:            firstOf(p.Socket, p.ID),
+				Manufacturer:    p.Manufacturer,
+				Model:           p.Model,
+				PartNumber:      p.PartNumber,
+				SerialNumber:    p.SerialNumber,
+				FirmwareVersion: p.FirmwareVersion,
+				Cores:           value(p.TotalCores),
+			})
+		}
+
+		memory, err := system.Memory()
+		if err != nil {
+			fail(fmt.Errorf("unable to read memory of system %s: %w", system.ID, err), ComponentDIMM)
+		}
+		for _, m := range memory {
+			add(system.ID, ComponentDIMM, m.Status, Component{
+				Slot:            firstOf(m.DeviceLocator, m.ID),
+				Manufacturer:    m.Manufacturer,
+				Model:           m.Model,
+				PartNumber:      m.PartNumber,
+				SerialNumber:    m.SerialNumber,
+				FirmwareVersion: m.FirmwareRevision,
+				CapacityBytes:   int64(value(m.CapacityMiB)) * 1024 * 1024,
+			})
+		}
+
+		storage, err := system.Storage()
+		if err != nil {
+			fail(fmt.Errorf("unable to read storage of system %s: %w", system.ID, err), ComponentStorageController, ComponentDrive)
+		}
+		for _, s := range storage {
+			controllers, err := s.Controllers()
+			if err != nil || len(controllers) == 0 {
+				for _, sc := range s.StorageControllers {
+					controllers = append(controllers, &sc)
+				}
+			}
+			for _, sc := range controllers {
+				add(system.ID, ComponentStorageController, sc.Status, Component{
+					Slot:            s.ID + "/" + firstOf(sc.ID, sc.Name),
+					Manufacturer:    sc.Manufacturer,
+					Model:           sc.Model,
+					PartNumber:      sc.PartNumber,
+					SerialNumber:    sc.SerialNumber,
+					FirmwareVersion: sc.FirmwareVersion,
+				})
+			}
+
+			drives, err := s.Drives()
+			if err != nil {
+				fail(fmt.Errorf("unable to read drives of storage %s: %w", s.ID, err), ComponentDrive)
+			}
+			for _, d := range drives {
+				add(system.ID, ComponentDrive, d.Status, Component{
+					Slot:            s.ID + "/" + d.ID,
+					Manufacturer:    d.Manufacturer,
+					Model:           d.Model,
+					PartNumber:      d.PartNumber,
+					SerialNumber:    d.SerialNumber,
+					FirmwareVersion: firstOf(d.FirmwareVersion, d.Revision),
+					CapacityBytes:   int64(value(d.CapacityBytes)),
+				})
+			}
+		}
+
+		nics, err := system.EthernetInterfaces()
+		if err != nil {
+			fail(fmt.Errorf("unable to read ethernet interfaces of system %s: %w", system.ID, err), ComponentNIC)
+		}
+		for _, n := range nics {
+			add(system.ID, ComponentNIC, n.Status, Component{
+				Slot:       n.ID,
+				MACAddress: strings.ToLower(firstOf(n.PermanentMACAddress, n.MACAddress)),
+			})
+		}
+
+		devices, err := system.PCIeDevices()
+		if err != nil {
+			fail(fmt.Errorf("unable to read pcie devices of system %s: %w", system.ID, err), ComponentPCIeDevice)
+		}
+		for _, d := range devices {
+			add(system.ID, ComponentPCIeDevice, d.Status, Component{
+				Slot:            d.ID,
+				Manufacturer:    d.Manufacturer,
+				Model:           d.Model,
+				PartNumber:      d.PartNumber,
+				SerialNumber:    d.SerialNumber,
+				FirmwareVersion: d.FirmwareVersion,
+			})
+		}
+	}
+
+	if len(inventory.Components) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		c.log.Debug("unable to read inventory", "error", err)
+	}
+
+	slices.Sort(inventory.Failed)
+	slices.SortFunc(inventory.Components, func(a, b Component) int {
+		return cmp.Compare(a.Key(), b.Key())
+	})
+	return inventory, nil
+}
+
+// firstOf returns the first non empty value.
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func value(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
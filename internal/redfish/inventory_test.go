@@ -0,0 +1,57 @@
+package redfish
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Inventory(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixtures string
+		want     *Inventory
+		wantErr  bool
+	}{
+		{
+			name:     "components with the same slots in multiple systems",
+			fixtures: "inventory",
+			want: &Inventory{
+				Components: []Component{
+					{System: "Node1", Kind: ComponentCPU, Slot: "CPU1", Manufacturer: "Intel(R) Corporation", Model: "Intel(R) Xeon(R) Silver 4214 CPU @ 2.20GHz", SerialNumber: "PRC-Node1", Cores: 12},
+					{System: "Node1", Kind: ComponentDIMM, Slot: "P1-DIMMA1", Manufacturer: "Samsung", PartNumber: "M393A4K40DB2-CVF", SerialNumber: "DIMM-1", CapacityBytes: 32 * 1024 * 1024 * 1024},
+					{System: "Node1", Kind: ComponentDrive, Slot: "1/Disk.0", Manufacturer: "Samsung", Model: "MZ7LH960HAJR", SerialNumber: "DRV-1", FirmwareVersion: "HXT7404Q", CapacityBytes: 960197124096},
+					{System: "Node1", Kind: ComponentNIC, Slot: "1", MACAddress: "ac:1f:6b:35:ac:61"},
+					{System: "Node1", Kind: ComponentPCIeDevice, Slot: "GPU1", Manufacturer: "NVIDIA", Model: "A100", SerialNumber: "GPU-1", FirmwareVersion: "92.00.25.00.08"},
+					{System: "Node1", Kind: ComponentStorageController, Slot: "1/HBA 9400-8i", Manufacturer: "Broadcom", Model: "HBA 9400-8i", FirmwareVersion: "14.00.00.00"},
+					{System: "Node2", Kind: ComponentCPU, Slot: "CPU1", Manufacturer: "Intel(R) Corporation", Model: "Intel(R) Xeon(R) Silver 4214 CPU @ 2.20GHz", SerialNumber: "PRC-Node2", Cores: 12},
+					{System: "Node2", Kind: ComponentNIC, Slot: "1", MACAddress: "ac:1f:6b:35:ac:62"},
+				},
+				// the memory of the second system can not be read
+				Failed: []ComponentKind{ComponentDIMM},
+			},
+		},
+		{
+			name:     "no systems",
+			fixtures: "empty",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := connectFixtures(t, tt.fixtures)
+
+			got, err := c.Inventory()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package redfish
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stmcginnis/gofish/schemas"
+)
+
+// Log is a log service of the bmc together with its entries
+type Log struct {
+	// ID is the redfish path of the log service
+	ID string
+	// SEL is true for the ipmi system event log
+	SEL        bool
+	MaxEntries int
+	Entries    []LogEntry
+}
+
+// LogEntry is an entry of a log service
+type LogEntry struct {
+	ID         string `json:"id"`
+	Created    string `json:"created,omitempty"`
+	Severity   string `json:"severity,omitempty"`
+	Message    string `json:"message,omitempty"`
+	MessageID  string `json:"message_id,omitempty"`
+	EntryType  string `json:"entry_type,omitempty"`
+	SensorType string `json:"sensor_type,omitempty"`
+}
+
+// Logs reads the entries of all log services of the systems and managers of the bmc.
+func (c *Client) Logs() ([]Log, error) {
+	service, cancel, err := c.service()
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	var (
+		services []*schemas.LogService
+		errs     []error
+	)
+
+	systems, err := service.Systems()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("unable to read systems: %w", err))
+	}
+	for _, system := range systems {
+		s, err := system.LogServices()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to read log services of system %s: %w", system.ID, err))
+			continue
+		}
+		services = append(services, s...)
+	}
+
+	managers, err := service.Managers()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("unable to read managers: %w", err))
+	}
+	for _, manager := range managers {
+		s, err := manager.LogServices()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to read log services of manager %s: %w", manager.ID, err))
+			continue
+		}
+		services = append(services, s...)
+	}
+
+	var logs []Log
+	for _, s := range services {
+		if !s.ServiceEnabled && s.Status.State == schemas.DisabledState {
+			continue
+		}
+		entries, err := s.Entries()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to read entries of log service %s: %w", s.ODataID, err))
+			continue
+		}
+
+		logs = append(logs, Log{
+			ID:         s.ODataID,
+			SEL:        s.LogEntryType == schemas.SELLogEntryTypes || strings.EqualFold(s.ID, "sel"),
+			MaxEntries: int(s.MaxNumberOfRecords),
+			Entries:    toLogEntries(entries),
+		})
+	}
+
+	if len(logs) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		c.log.Debug("unable to read logs", "error", err)
+	}
+	return logs, nil
+}
+
+// LogEntries reads the entries of the log service with the given id.
+func (c *Client) LogEntries(id string) ([]LogEntry, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	s, err := schemas.GetLogService(c.client.WithContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+	return toLogEntries(entries), nil
+}
+
+func toLogEntries(entries []*schemas.LogEntry) []LogEntry {
+	var result []LogEntry
+	for _, e := range entries {
+		result = append(result, LogEntry{
+			ID:         e.ID,
+			Created:    e.Created,
+			Severity:   string(e.Severity),
+			Message:    e.Message,
+			MessageID:  e.MessageID,
+			EntryType:  string(e.EntryType),
+			SensorType: string(e.SensorType),
+		})
+	}
+	return result
+}
+
+// ClearLog removes all entries of the log service with the given id.
+func (c *Client) ClearLog(id string) error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	s, err := schemas.GetLogService(c.client.WithContext(ctx), id)
+	if err != nil {
+		return err
+	}
+	if !s.SupportsClearLog() {
+		return fmt.Errorf("log service %s can not be cleared", id)
+	}
+	_, err = s.ClearLog("")
+	return err
+}
@@ -0,0 +1,237 @@
+package redfish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/stmcginnis/gofish"
+	"github.com/stmcginnis/gofish/schemas"
+)
+
+const (
+	administratorRole = "Administrator"
+)
+
+// Client is a redfish client for a single bmc, it offers what go-hal does not provide.
+type Client struct {
+	log     *slog.Logger
+	client  *gofish.APIClient
+	timeout time.Duration
+}
+
+// Connect connects to the redfish api of the bmc at the given host, ipv6 addresses must be enclosed in brackets.
+func Connect(log *slog.Logger, host, user, password string, timeout time.Duration) (*Client, error) {
+	return connect(log, host, user, password, timeout, true)
+}
+
+// ConnectSession connects like Connect but authenticates with a redfish session instead of basic auth.
+// On most bmcs the session stays valid when the password of its user is changed, which allows to roll back.
+func ConnectSession(log *slog.Logger, host, user, password string, timeout time.Duration) (*Client, error) {
+	return connect(log, host, user, password, timeout, false)
+}
+
+func connect(log *slog.Logger, host, user, password string, timeout time.Duration, basicAuth bool) (*Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c, err := gofish.ConnectContext(ctx, gofish.ClientConfig{
+		Endpoint:  "https://" + host,
+		Username:  user,
+		Password:  password,
+		Insecure:  true,
+		BasicAuth: basicAuth,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to establish redfish connection to %s with user %s: %w", host, user, err)
+	}
+
+	return &Client{
+		log:     log,
+		client:  c,
+		timeout: timeout,
+	}, nil
+}
+
+// Close terminates the redfish session.
+func (c *Client) Close() {
+	c.client.Logout()
+}
+
+func (c *Client) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+func (c *Client) service() (*gofish.Service, context.CancelFunc, error) {
+	ctx, cancel := c.context()
+	g := c.client.WithContext(ctx)
+	if g.Service == nil {
+		cancel()
+		return nil, nil, fmt.Errorf("redfish service root is not available")
+	}
+	return g.Service, cancel, nil
+}
+
+// Verify checks that the credentials of the connection are accepted by reading the accounts of the bmc.
+func (c *Client) Verify() error {
+	service, cancel, err := c.service()
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	accountService, err := service.AccountService()
+	if err != nil {
+		return err
+	}
+	_, err = accountService.Accounts()
+	return err
+}
+
+// CreateAdministrator creates an enabled administrator account with the given name and password.
+// If the bmc does not support creating accounts, the first unused predefined account slot is used.
+func (c *Client) CreateAdministrator(userName, password string) error {
+	service, cancel, err := c.service()
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	accountService, err := service.AccountService()
+	if err != nil {
+		return err
+	}
+
+	_, createErr := accountService.CreateAccount(userName, password, administratorRole)
+	if createErr == nil {
+		return nil
+	}
+
+	// some vendors have a fixed number of account slots which must be patched instead
+	accounts, err := accountService.Accounts()
+	if err != nil {
+		return errors.Join(createErr, err)
+	}
+	for _, account := range accounts {
+		if account.UserName != "" || account.ID == "1" {
+			continue
+		}
+		c.log.Debug("creating account in predefined slot", "slot", account.ID, "user", userName)
+		account.UserName = userName
+		account.Password = password
+		account.RoleID = administratorRole
+		account.Enabled = true
+		return account.Update()
+	}
+
+	return fmt.Errorf("unable to create account: %w", createErr)
+}
+
+// AccountNames returns the names of all accounts of the bmc, unused predefined account slots are left out.
+func (c *Client) AccountNames() ([]string, error) {
+	service, cancel, err := c.service()
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	accountService, err := service.AccountService()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := accountService.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, account := range accounts {
+		if account.UserName != "" {
+			names = append(names, account.UserName)
+		}
+	}
+	return names, nil
+}
+
+// DisableAccount disables the account with the given name, the account is kept.
+func (c *Client) DisableAccount(userName string) error {
+	account, cancel, err := c.account(userName)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	account.Enabled = false
+	return account.Update()
+}
+
+// ChangePassword changes the password of the account with the given name.
+func (c *Client) ChangePassword(userName, password string) error {
+	account, cancel, err := c.account(userName)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	account.Password = password
+	return account.Update()
+}
+
+// DeleteAccount deletes the account with the given name, predefined account slots are cleared and disabled.
+func (c *Client) DeleteAccount(userName string) error {
+	account, cancel, err := c.account(userName)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	// the account is bound to the client with the timeout of this call
+	resp, err := account.GetClient().Delete(account.ODataID)
+	if resp != nil {
+		_ = resp.Body.Close()
+	}
+	if err == nil {
+		return nil
+	}
+
+	var redfishErr *schemas.Error
+	if errors.As(err, &redfishErr) && redfishErr.HTTPReturnedStatusCode != http.StatusMethodNotAllowed {
+		return err
+	}
+
+	account.Enabled = false
+	account.UserName = ""
+	return account.Update()
+}
+
+func (c *Client) account(userName string) (*schemas.ManagerAccount, context.CancelFunc, error) {
+	service, cancel, err := c.service()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accountService, err := service.AccountService()
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	accounts, err := accountService.Accounts()
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	for _, account := range accounts {
+		if account.UserName == userName {
+			return account, cancel, nil
+		}
+	}
+
+	cancel()
+	return nil, nil, fmt.Errorf("account %q not found", userName)
+}
@@ -0,0 +1,44 @@
+package redfish
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newFixtureServer serves the recorded redfish responses of testdata/<fixtures>, the response of a path is
+// stored in <path>.json, paths without a file are answered with not found.
+func newFixtureServer(t *testing.T, fixtures string) *httptest.Server {
+	dir := filepath.Join("testdata", fixtures)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(strings.TrimSuffix(req.URL.Path, "/")+".json")))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// connectFixtures connects a client to a server with the given fixtures
+func connectFixtures(t *testing.T, fixtures string) *Client {
+	server := newFixtureServer(t, fixtures)
+	c, err := Connect(slog.Default(), strings.TrimPrefix(server.URL, "https://"), "admin", "secret", 5*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	return c
+}
@@ -0,0 +1,161 @@
+package redfish
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/stmcginnis/gofish/schemas"
+)
+
+// SensorType is the kind of value a sensor measures
+type SensorType string
+
+const (
+	SensorTemperature SensorType = "temperature"
+	SensorFan         SensorType = "fan"
+	SensorVoltage     SensorType = "voltage"
+	SensorPower       SensorType = "power"
+)
+
+// Sensor is the reading of a sensor of a chassis
+type Sensor struct {
+	Type SensorType
+	// Chassis is the id of the chassis the sensor belongs to, sensor names are only unique within a chassis
+	Chassis string
+	Name    string
+	// Reading in the unit of the sensor type, fans are either reported in RPM or percent
+	Reading float64
+	Unit    string
+	// Health as reported by the bmc, either OK, Warning, Critical or empty if unknown
+	Health        string
+	LowerCritical *float64
+	UpperCritical *float64
+}
+
+// Sensors reads the temperature, fan, voltage and power sensors of all chassis of the bmc.
+func (c *Client) Sensors() ([]Sensor, error) {
+	service, cancel, err := c.service()
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	chassis, err := service.Chassis()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		sensors []Sensor
+		errs    []error
+	)
+	for _, ch := range chassis {
+		thermal, err := ch.Thermal()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to read thermal of chassis %s: %w", ch.ID, err))
+		} else if thermal != nil {
+			sensors = append(sensors, thermalSensors(ch.ID, thermal)...)
+		}
+
+		power, err := ch.Power()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to read power of chassis %s: %w", ch.ID, err))
+		} else if power != nil {
+			sensors = append(sensors, powerSensors(ch.ID, power)...)
+		}
+	}
+
+	if len(sensors) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		c.log.Debug("unable to read sensors", "error", err)
+	}
+	return sensors, nil
+}
+
+func thermalSensors(chassis string, thermal *schemas.Thermal) []Sensor {
+	var sensors []Sensor
+	for _, t := range thermal.Temperatures {
+		if t.ReadingCelsius == nil {
+			continue
+		}
+		sensors = append(sensors, Sensor{
+			Type:          SensorTemperature,
+			Chassis:       chassis,
+			Name:          name(t.Name, t.MemberID),
+			Reading:       *t.ReadingCelsius,
+			Unit:          "Cel",
+			Health:        string(t.Status.Health),
+			LowerCritical: t.LowerThresholdCritical,
+			UpperCritical: t.UpperThresholdCritical,
+		})
+	}
+	for _, f := range thermal.Fans {
+		if f.Reading == nil {
+			continue
+		}
+		unit := string(f.ReadingUnits)
+		if unit == "" {
+			unit = string(schemas.RPMReadingUnits)
+		}
+		sensors = append(sensors, Sensor{
+			Type:          SensorFan,
+			Chassis:       chassis,
+			Name:          name(f.Name, name(f.FanName, f.MemberID)),
+			Reading:       float64(*f.Reading),
+			Unit:          unit,
+			Health:        string(f.Status.Health),
+			LowerCritical: toFloat64(f.LowerThresholdCritical),
+			UpperCritical: toFloat64(f.UpperThresholdCritical),
+		})
+	}
+	return sensors
+}
+
+func powerSensors(chassis string, power *schemas.Power) []Sensor {
+	var sensors []Sensor
+	for _, v := range power.Voltages {
+		if v.ReadingVolts == nil {
+			continue
+		}
+		sensors = append(sensors, Sensor{
+			Type:          SensorVoltage,
+			Chassis:       chassis,
+			Name:          name(v.Name, v.MemberID),
+			Reading:       float64(*v.ReadingVolts),
+			Unit:          "V",
+			Health:        string(v.Status.Health),
+			LowerCritical: toFloat64(v.LowerThresholdCritical),
+			UpperCritical: toFloat64(v.UpperThresholdCritical),
+		})
+	}
+	for _, p := range power.PowerControl {
+		if p.PowerConsumedWatts == nil {
+			continue
+		}
+		sensors = append(sensors, Sensor{
+			Type:    SensorPower,
+			Chassis: chassis,
+			Name:    name(p.Name, p.MemberID),
+			Reading: float64(*p.PowerConsumedWatts),
+			Unit:    "W",
+			Health:  string(p.Status.Health),
+		})
+	}
+	return sensors
+}
+
+func name(n, fallback string) string {
+	if n != "" {
+		return n
+	}
+	return fallback
+}
+
+func toFloat64[T int | float32](v *T) *float64 {
+	if v == nil {
+		return nil
+	}
+	return new(float64(*v))
+}
@@ -0,0 +1,56 @@
+package redfish
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Sensors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixtures string
+		want     []Sensor
+		wantErr  bool
+	}{
+		{
+			name:     "sensors with the same names in multiple chassis",
+			fixtures: "sensors",
+			want: []Sensor{
+				{Type: SensorTemperature, Chassis: "1", Name: "CPU1 Temp", Reading: 42, Unit: "Cel", Health: "OK", UpperCritical: new(90.0)},
+				{Type: SensorFan, Chassis: "1", Name: "FAN1", Reading: 3400, Unit: "RPM", Health: "OK", LowerCritical: new(700.0)},
+				{Type: SensorVoltage, Chassis: "1", Name: "12V", Reading: float64(float32(12.1)), Unit: "V", Health: "OK", LowerCritical: new(float64(float32(10.8))), UpperCritical: new(float64(float32(13.2)))},
+				{Type: SensorPower, Chassis: "1", Name: "System Power Control", Reading: 230, Unit: "W", Health: "OK"},
+				// the power of the second chassis can not be read, which is only logged
+				{Type: SensorTemperature, Chassis: "2", Name: "CPU1 Temp", Reading: 51, Unit: "Cel", Health: "OK", UpperCritical: new(90.0)},
+				{Type: SensorFan, Chassis: "2", Name: "FAN1", Reading: 35, Unit: "Percent", Health: "Critical"},
+			},
+		},
+		{
+			name:     "no chassis",
+			fixtures: "empty",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := connectFixtures(t, tt.fixtures)
+
+			got, err := c.Sensors()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			// the chassis are read in any order
+			slices.SortStableFunc(got, func(a, b Sensor) int { return strings.Compare(a.Chassis, b.Chassis) })
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
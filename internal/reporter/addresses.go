@@ -0,0 +1,94 @@
+package reporter
+
+import (
+	"slices"
+	"time"
+
+	"github.com/metal-stack/metal-bmc/internal/leases"
+)
+
+// addressHistory contains the last known bmc address of a machine and the times it changed within the flapping window
+type addressHistory struct {
+	address BMCAddress
+	changes []time.Time
+	// missed is the number of cycles of all devices since the bmc was seen last
+	missed int
+}
+
+// trackAddresses publishes an event for every machine whose bmc got another mac or ip since it was seen last.
+// Machines whose bmc address changed too often within the flapping window are flagged as flapping.
+func (r *reporter) trackAddresses(items []*leases.ReportItem, now time.Time) {
+	for uuid, bmcs := range bmcsByUUID(items) {
+		if _, ok := r.conflicts[uuid]; ok || len(bmcs) != 1 {
+			// a conflicting uuid has no single address
+			continue
+		}
+		current := bmcs[0]
+
+		h, ok := r.addresses[uuid]
+		if !ok {
+			r.addresses[uuid] = &addressHistory{address: current}
+			continue
+		}
+		if h.address == current {
+			continue
+		}
+
+		previous := h.address
+		h.address = current
+		h.changes = slices.DeleteFunc(h.changes, func(t time.Time) bool {
+			return now.Sub(t) > r.cfg.AddressFlappingWindow
+		})
+		h.changes = append(h.changes, now)
+		flapping := r.cfg.AddressFlappingChanges > 0 && len(h.changes) >= r.cfg.AddressFlappingChanges
+
+		bmcAddressChanges.WithLabelValues(uuid).Inc()
+		if flapping {
+			r.log.Warn("bmc address of machine is flapping", "uuid", uuid, "mac", current.Mac, "ip", current.Ip, "previous mac", previous.Mac, "previous ip", previous.Ip, "changes", len(h.changes), "window", r.cfg.AddressFlappingWindow)
+		} else {
+			r.log.Info("bmc address of machine changed", "uuid", uuid, "mac", current.Mac, "ip", current.Ip, "previous mac", previous.Mac, "previous ip", previous.Ip)
+		}
+		r.publish(Event{Type: EventAddressChanged, UUID: uuid, Mac: current.Mac, Ip: current.Ip, PreviousAddress: &previous, Flapping: flapping})
+	}
+}
+
+// detectMissing marks the machines whose bmc had no active lease for the configured number of cycles as missing,
+// e.g. because the management cable was unplugged or the bmc is dead. A bmc is seen as long as its last known mac has an active lease.
+// Devices of the static inventory always have a lease, they are only seen if they could be read.
+// It must only be called with the results of all devices.
+func (r *reporter) detectMissing(results []deviceResult) {
+	if r.cfg.MissingAfterCycles <= 0 {
+		return
+	}
+
+	active := make(map[string]bool)
+	for _, result := range results {
+		if _, ok := r.static.Device(result.Mac); ok && (result.Status == deviceStatusFailed || result.Status == deviceStatusSkipped) {
+			// a skipped device is backed off because it failed before
+			continue
+		}
+		active[result.Mac] = true
+	}
+
+	missingBMCs.Reset()
+	for uuid, h := range r.addresses {
+		if active[h.address.Mac] {
+			if h.missed >= r.cfg.MissingAfterCycles {
+				r.log.Info("bmc of missing machine reappeared", "uuid", uuid, "mac", h.address.Mac, "ip", h.address.Ip)
+				r.publish(Event{Type: EventReappeared, UUID: uuid, Mac: h.address.Mac, Ip: h.address.Ip})
+			}
+			h.missed = 0
+			continue
+		}
+
+		h.missed++
+		if h.missed < r.cfg.MissingAfterCycles {
+			continue
+		}
+		if h.missed == r.cfg.MissingAfterCycles {
+			r.log.Warn("bmc of machine is missing", "uuid", uuid, "mac", h.address.Mac, "ip", h.address.Ip, "cycles", h.missed)
+			r.publish(Event{Type: EventMissing, UUID: uuid, Mac: h.address.Mac, Ip: h.address.Ip})
+		}
+		missingBMCs.WithLabelValues(uuid, h.address.Mac, h.address.Ip).Set(float64(h.missed))
+	}
+}
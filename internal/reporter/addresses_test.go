@@ -0,0 +1,120 @@
+package reporter
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_reporter_trackAddresses(t *testing.T) {
+	publisher := &testPublisher{}
+	r := &reporter{
+		cfg: &config.Config{
+			EventTopic:             "bmc-event",
+			AddressFlappingChanges: 2,
+			AddressFlappingWindow:  time.Hour,
+		},
+		log:       slog.Default(),
+		publisher: publisher,
+		conflicts: map[string][]BMCAddress{"b": nil},
+		addresses: make(map[string]*addressHistory),
+	}
+
+	item := func(mac, ip, uuid string) []*leases.ReportItem {
+		return []*leases.ReportItem{
+			{Lease: leases.Lease{Mac: mac, Ip: ip}, UUID: new(uuid)},
+			// conflicting uuids are not tracked
+			{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:70", Ip: "10.0.0.10"}, UUID: new("b")},
+		}
+	}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	r.trackAddresses(item("ac:1f:6b:35:ac:62", "10.0.0.1", "a"), now)
+	r.trackAddresses(item("ac:1f:6b:35:ac:62", "10.0.0.1", "a"), now.Add(time.Minute))
+	r.trackAddresses(item("ac:1f:6b:35:ac:62", "10.0.0.2", "a"), now.Add(2*time.Minute))
+	r.trackAddresses(item("ac:1f:6b:35:ac:63", "10.0.0.2", "a"), now.Add(3*time.Minute))
+	// the previous changes are outside of the flapping window
+	r.trackAddresses(item("ac:1f:6b:35:ac:63", "10.0.0.3", "a"), now.Add(2*time.Hour))
+
+	want := []Event{
+		{Type: EventAddressChanged, UUID: "a", Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.2", PreviousAddress: &BMCAddress{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"}},
+		{Type: EventAddressChanged, UUID: "a", Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.2", PreviousAddress: &BMCAddress{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.2"}, Flapping: true},
+		{Type: EventAddressChanged, UUID: "a", Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.3", PreviousAddress: &BMCAddress{Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.2"}},
+	}
+	if diff := cmp.Diff(want, publisher.events, cmpopts.IgnoreFields(Event{}, "Time")); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
+
+func Test_reporter_detectMissing(t *testing.T) {
+	publisher := &testPublisher{}
+	r := &reporter{
+		cfg:       &config.Config{EventTopic: "bmc-event", MissingAfterCycles: 2},
+		log:       slog.Default(),
+		publisher: publisher,
+		addresses: map[string]*addressHistory{
+			"a": {address: BMCAddress{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"}},
+		},
+	}
+
+	// the bmc is seen as long as it has a lease, even if its details could not be read
+	seen := []deviceResult{{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1", Status: deviceStatusFailed}}
+
+	r.detectMissing(seen)
+	r.detectMissing(nil)
+	assert.Equal(t, 0, testutil.CollectAndCount(missingBMCs))
+	r.detectMissing(nil)
+	r.detectMissing(nil)
+	assert.InDelta(t, 3, testutil.ToFloat64(missingBMCs.WithLabelValues("a", "ac:1f:6b:35:ac:62", "10.0.0.1")), 0.001)
+	r.detectMissing(seen)
+	assert.Equal(t, 0, testutil.CollectAndCount(missingBMCs))
+
+	want := []Event{
+		{Type: EventMissing, UUID: "a", Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"},
+		{Type: EventReappeared, UUID: "a", Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"},
+	}
+	if diff := cmp.Diff(want, publisher.events, cmpopts.IgnoreFields(Event{}, "Time")); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
+
+func Test_reporter_detectMissing_static(t *testing.T) {
+	inventoryFile := filepath.Join(t.TempDir(), "static.yaml")
+	require.NoError(t, os.WriteFile(inventoryFile, []byte("- mac: ac:1f:6b:35:ac:62\n  ip: 10.0.0.1\n"), 0o600))
+	static, err := leases.NewStaticInventory(slog.Default(), inventoryFile)
+	require.NoError(t, err)
+
+	publisher := &testPublisher{}
+	r := &reporter{
+		cfg:       &config.Config{EventTopic: "bmc-event", MissingAfterCycles: 2},
+		log:       slog.Default(),
+		publisher: publisher,
+		static:    static,
+		addresses: map[string]*addressHistory{
+			"a": {address: BMCAddress{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"}},
+		},
+	}
+
+	// a static device always has a lease, it is only seen if it could be read
+	r.detectMissing([]deviceResult{{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1", Status: deviceStatusFailed}})
+	r.detectMissing([]deviceResult{{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1", Status: deviceStatusSkipped}})
+	r.detectMissing([]deviceResult{{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1", Status: deviceStatusPartial}})
+
+	want := []Event{
+		{Type: EventMissing, UUID: "a", Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"},
+		{Type: EventReappeared, UUID: "a", Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"},
+	}
+	if diff := cmp.Diff(want, publisher.events, cmpopts.IgnoreFields(Event{}, "Time")); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
@@ -0,0 +1,128 @@
+package reporter
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/metal-stack/metal-bmc/internal/leases"
+)
+
+// enrichmentBackoff tracks failed enrichments per mac address and delays further attempts exponentially.
+// Devices which failed too often in a row are quarantined and only retried with the maximum backoff.
+type enrichmentBackoff struct {
+	lock    sync.Mutex
+	devices map[string]*deviceBackoff
+
+	initial         time.Duration
+	max             time.Duration
+	quarantineAfter int
+}
+
+type deviceBackoff struct {
+	ip          string
+	failures    int
+	lastError   string
+	nextAttempt time.Time
+	quarantined bool
+}
+
+// quarantinedDevice is a device which did not answer for a longer time
+type quarantinedDevice struct {
+	Mac       string
+	Ip        string
+	Failures  int
+	LastError string
+}
+
+func newEnrichmentBackoff(initial, max time.Duration, quarantineAfter int) *enrichmentBackoff {
+	return &enrichmentBackoff{
+		devices:         make(map[string]*deviceBackoff),
+		initial:         initial,
+		max:             max,
+		quarantineAfter: quarantineAfter,
+	}
+}
+
+// shouldAttempt returns true if the enrichment of the device with the given mac should be attempted at the given time.
+func (b *enrichmentBackoff) shouldAttempt(mac string, now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	d, ok := b.devices[mac]
+	if !ok {
+		return true
+	}
+	return !now.Before(d.nextAttempt)
+}
+
+// failure records a failed enrichment and returns the time of the next attempt.
+func (b *enrichmentBackoff) failure(lease leases.Lease, err error, now time.Time) time.Time {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	d, ok := b.devices[lease.Mac]
+	if !ok {
+		d = &deviceBackoff{}
+		b.devices[lease.Mac] = d
+	}
+
+	d.ip = lease.Ip
+	d.failures++
+	d.lastError = err.Error()
+
+	delay := b.max
+	if d.failures < 32 {
+		delay = min(b.initial*time.Duration(1<<(d.failures-1)), b.max)
+	}
+	if b.quarantineAfter > 0 && d.failures >= b.quarantineAfter {
+		d.quarantined = true
+		delay = b.max
+	}
+
+	d.nextAttempt = now.Add(delay)
+	return d.nextAttempt
+}
+
+// isQuarantined returns true if the device with the given mac is quarantined.
+func (b *enrichmentBackoff) isQuarantined(mac string) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	d, ok := b.devices[mac]
+	return ok && d.quarantined
+}
+
+// success resets the backoff of the device with the given mac.
+func (b *enrichmentBackoff) success(mac string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.devices, mac)
+}
+
+// quarantined returns all devices which are currently quarantined sorted by mac address.
+func (b *enrichmentBackoff) quarantined() []quarantinedDevice {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var result []quarantinedDevice
+	for mac, d := range b.devices {
+		if !d.quarantined {
+			continue
+		}
+		result = append(result, quarantinedDevice{
+			Mac:       mac,
+			Ip:        d.ip,
+			Failures:  d.failures,
+			LastError: d.lastError,
+		})
+	}
+
+	slices.SortFunc(result, func(a, b quarantinedDevice) int {
+		return strings.Compare(a.Mac, b.Mac)
+	})
+
+	return result
+}
@@ -0,0 +1,45 @@
+package reporter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_enrichmentBackoff(t *testing.T) {
+	var (
+		now   = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		lease = leases.Lease{Mac: "aa:aa", Ip: "10.0.0.1"}
+		b     = newEnrichmentBackoff(time.Minute, 10*time.Minute, 4)
+		err   = fmt.Errorf("connection refused")
+	)
+
+	assert.True(t, b.shouldAttempt(lease.Mac, now))
+
+	wantDelays := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for i, want := range wantDelays {
+		next := b.failure(lease, err, now)
+		assert.Equal(t, now.Add(want), next, "failure %d", i+1)
+		assert.False(t, b.shouldAttempt(lease.Mac, now.Add(want-time.Second)))
+		assert.True(t, b.shouldAttempt(lease.Mac, now.Add(want)))
+		assert.True(t, b.shouldAttempt("bb:bb", now), "other devices are not affected")
+	}
+
+	want := []quarantinedDevice{
+		{Mac: "aa:aa", Ip: "10.0.0.1", Failures: 5, LastError: "connection refused"},
+	}
+	if diff := cmp.Diff(want, b.quarantined()); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+	assert.True(t, b.isQuarantined(lease.Mac))
+	assert.False(t, b.isQuarantined("bb:bb"))
+
+	b.success(lease.Mac)
+	assert.True(t, b.shouldAttempt(lease.Mac, now))
+	assert.Empty(t, b.quarantined())
+	assert.False(t, b.isQuarantined(lease.Mac))
+}
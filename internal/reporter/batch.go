@@ -0,0 +1,124 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/metal-stack/metal-go/api/client/machine"
+	"github.com/metal-stack/metal-go/api/models"
+	"golang.org/x/sync/errgroup"
+)
+
+// batchResult is the aggregated result of all batches of a report
+type batchResult struct {
+	// reported contains the uuids of all machines which were part of a successful batch
+	reported map[string]bool
+	updated  []string
+	created  []string
+}
+
+// splitReports splits the reports into batches of the given size, the reports are ordered by uuid.
+// If size is zero or negative all reports are put into a single batch.
+func splitReports(reports map[string]models.V1MachineIpmiReport, size int) []map[string]models.V1MachineIpmiReport {
+	if len(reports) == 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = len(reports)
+	}
+
+	var batches []map[string]models.V1MachineIpmiReport
+	for chunk := range slices.Chunk(slices.Sorted(maps.Keys(reports)), size) {
+		batch := make(map[string]models.V1MachineIpmiReport, len(chunk))
+		for _, uuid := range chunk {
+			batch[uuid] = reports[uuid]
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// sendBatches sends the reports in batches to the metal-api, batches are sent in parallel and retried individually.
+// The result contains all successfully reported batches, an error is returned if at least one batch failed.
+func (r *reporter) sendBatches(reports map[string]models.V1MachineIpmiReport) (*batchResult, error) {
+	var (
+		batches = splitReports(reports, r.cfg.ReportBatchSize)
+		result  = &batchResult{reported: make(map[string]bool)}
+		lock    sync.Mutex
+		errs    []error
+	)
+
+	g := new(errgroup.Group)
+	g.SetLimit(max(r.cfg.ReportBatchParallelism, 1))
+	for i, batch := range batches {
+		g.Go(func() error {
+			resp, err := r.sendBatch(batch)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if err != nil {
+				r.log.Error("unable to send batch of ipmi reports", "batch", i+1, "of", len(batches), "# of reports", len(batch), "error", err)
+				errs = append(errs, fmt.Errorf("batch %d of %d failed: %w", i+1, len(batches), err))
+				return nil
+			}
+
+			for uuid := range batch {
+				result.reported[uuid] = true
+			}
+			result.updated = append(result.updated, resp.Updated...)
+			result.created = append(result.created, resp.Created...)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	slices.Sort(result.updated)
+	slices.Sort(result.created)
+
+	return result, errors.Join(errs...)
+}
+
+// sendBatch sends a single batch of reports and retries it with an exponential backoff on failure.
+func (r *reporter) sendBatch(batch map[string]models.V1MachineIpmiReport) (*models.V1MachineIpmiReportResponse, error) {
+	mir := &models.V1MachineIpmiReports{
+		Partitionid: r.cfg.PartitionID,
+		Reports:     batch,
+	}
+
+	var err error
+	for attempt := range r.cfg.ReportBatchRetries + 1 {
+		if attempt > 0 {
+			delay := retryDelay(r.cfg.ReportBatchRetryDelay, r.cfg.ReportBatchMaxRetryDelay, attempt)
+			r.log.Warn("retrying batch of ipmi reports", "attempt", attempt, "delay", delay.String(), "error", err)
+			time.Sleep(delay)
+		}
+
+		var ok *machine.IpmiReportOK
+		ok, err = r.client.Machine().IpmiReport(machine.NewIpmiReportParams().WithBody(mir), nil)
+		if err == nil {
+			return ok.Payload, nil
+		}
+	}
+
+	return nil, err
+}
+
+// retryDelay returns the delay before the given retry attempt, the initial delay is doubled on every attempt up to max.
+func retryDelay(initial, max time.Duration, attempt int) time.Duration {
+	delay := initial
+	for range attempt - 1 {
+		delay *= 2
+		if max > 0 && delay >= max {
+			return max
+		}
+	}
+	if max > 0 {
+		return min(delay, max)
+	}
+	return delay
+}
@@ -0,0 +1,105 @@
+package reporter
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/metal-stack/metal-go/api/client/machine"
+	"github.com/metal-stack/metal-go/api/models"
+	"github.com/metal-stack/metal-go/test/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_splitReports(t *testing.T) {
+	reports := map[string]models.V1MachineIpmiReport{
+		"c": {}, "a": {}, "b": {}, "e": {}, "d": {},
+	}
+
+	batches := splitReports(reports, 2)
+	require.Len(t, batches, 3)
+
+	var got [][]string
+	for _, batch := range batches {
+		keys := make([]string, 0, len(batch))
+		for uuid := range batch {
+			keys = append(keys, uuid)
+		}
+		slices.Sort(keys)
+		got = append(got, keys)
+	}
+
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	assert.Len(t, splitReports(reports, 0), 1)
+	assert.Empty(t, splitReports(nil, 2))
+}
+
+func Test_reporter_sendBatches(t *testing.T) {
+	reports := map[string]models.V1MachineIpmiReport{
+		"a": {}, "b": {}, "c": {},
+	}
+
+	containsUUID := func(uuid string) any {
+		return mock.MatchedBy(func(params *machine.IpmiReportParams) bool {
+			_, ok := params.Body.Reports[uuid]
+			return ok
+		})
+	}
+
+	_, metalClient := client.NewMetalMockClient(t, &client.MetalMockFns{
+		Machine: func(m *mock.Mock) {
+			m.On("IpmiReport", containsUUID("a"), nil).Return(&machine.IpmiReportOK{
+				Payload: &models.V1MachineIpmiReportResponse{
+					Updated: []string{"a"},
+					Created: []string{"b"},
+				},
+			}, nil).Once()
+			// the second batch fails on every attempt
+			m.On("IpmiReport", containsUUID("c"), nil).Return(nil, fmt.Errorf("service unavailable")).Times(2)
+		},
+	})
+
+	r := &reporter{
+		cfg: &config.Config{
+			PartitionID:            "partition",
+			ReportBatchSize:        2,
+			ReportBatchParallelism: 2,
+			ReportBatchRetries:     1,
+		},
+		log:    slog.Default(),
+		client: metalClient,
+	}
+
+	result, err := r.sendBatches(reports)
+	require.EqualError(t, err, "batch 2 of 2 failed: service unavailable")
+
+	want := &batchResult{
+		reported: map[string]bool{"a": true, "b": true},
+		updated:  []string{"a"},
+		created:  []string{"b"},
+	}
+	if diff := cmp.Diff(want, result, cmp.AllowUnexported(batchResult{})); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
+
+func Test_retryDelay(t *testing.T) {
+	var got []time.Duration
+	for attempt := 1; attempt <= 6; attempt++ {
+		got = append(got, retryDelay(time.Second, 10*time.Second, attempt))
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
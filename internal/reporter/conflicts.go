@@ -0,0 +1,84 @@
+package reporter
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/metal-stack/metal-bmc/internal/leases"
+)
+
+// updateConflicts detects machine uuids which are reported by more than one bmc, e.g. after a board swap or a cloned bmc configuration.
+// Conflicting uuids are left out of the reports until a cycle of all devices finds a single bmc with the uuid
+// and every other candidate was read with another uuid or is gone.
+func (r *reporter) updateConflicts(items []*leases.ReportItem, partial bool) {
+	byUUID := bmcsByUUID(items)
+
+	for uuid, bmcs := range byUUID {
+		if len(bmcs) < 2 {
+			continue
+		}
+		known := r.conflicts[uuid]
+		candidates := mergeCandidates(known, bmcs)
+		if slices.Equal(known, candidates) {
+			continue
+		}
+		r.conflicts[uuid] = candidates
+		r.log.Error("machine uuid is reported by multiple bmcs, leaving it out of the report until the conflict is resolved", "uuid", uuid, "bmcs", candidates)
+		r.publish(Event{Type: EventUUIDConflict, UUID: uuid, Candidates: candidates})
+	}
+
+	if partial {
+		// a partial cycle might miss a candidate
+		return
+	}
+
+	// the uuid of devices which could not be read is unknown, they might still be a candidate
+	unread := make(map[string]bool)
+	for _, item := range items {
+		if item.UUID == nil {
+			unread[item.Lease.Mac] = true
+		}
+	}
+
+	for uuid, candidates := range r.conflicts {
+		if len(byUUID[uuid]) > 1 || slices.ContainsFunc(candidates, func(c BMCAddress) bool { return unread[c.Mac] }) {
+			continue
+		}
+		delete(r.conflicts, uuid)
+		r.log.Info("machine uuid conflict is resolved", "uuid", uuid, "bmcs", byUUID[uuid])
+		r.publish(Event{Type: EventUUIDConflictResolved, UUID: uuid, Candidates: byUUID[uuid]})
+	}
+}
+
+// bmcsByUUID returns the bmcs of the items by machine uuid, sorted by mac.
+func bmcsByUUID(items []*leases.ReportItem) map[string][]BMCAddress {
+	byUUID := make(map[string][]BMCAddress)
+	for _, item := range items {
+		if item.UUID == nil {
+			continue
+		}
+		byUUID[*item.UUID] = append(byUUID[*item.UUID], BMCAddress{Mac: item.Lease.Mac, Ip: item.Lease.Ip})
+	}
+	for _, bmcs := range byUUID {
+		sortAddresses(bmcs)
+	}
+	return byUUID
+}
+
+// mergeCandidates adds the known candidates which are not part of the current candidates, the addresses of the current candidates take precedence.
+func mergeCandidates(known, current []BMCAddress) []BMCAddress {
+	merged := slices.Clone(current)
+	for _, k := range known {
+		if !slices.ContainsFunc(merged, func(c BMCAddress) bool { return c.Mac == k.Mac }) {
+			merged = append(merged, k)
+		}
+	}
+	sortAddresses(merged)
+	return merged
+}
+
+func sortAddresses(addresses []BMCAddress) {
+	slices.SortFunc(addresses, func(a, b BMCAddress) int {
+		return strings.Compare(a.Mac, b.Mac)
+	})
+}
@@ -0,0 +1,73 @@
+package reporter
+
+import (
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+type testPublisher struct {
+	events []Event
+}
+
+func (p *testPublisher) Publish(_ string, body []byte) error {
+	var e Event
+	err := json.Unmarshal(body, &e)
+	if err != nil {
+		return err
+	}
+	p.events = append(p.events, e)
+	return nil
+}
+
+func Test_reporter_updateConflicts(t *testing.T) {
+	publisher := &testPublisher{}
+	r := &reporter{
+		cfg:       &config.Config{EventTopic: "bmc-event"},
+		log:       slog.Default(),
+		publisher: publisher,
+		conflicts: make(map[string][]BMCAddress),
+		bmcMacs:   newBMCMacs(),
+	}
+
+	a := &leases.ReportItem{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"}, UUID: new("a")}
+	cloned := &leases.ReportItem{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.2"}, UUID: new("a")}
+	b := &leases.ReportItem{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:64", Ip: "10.0.0.3"}, UUID: new("b")}
+	candidates := []BMCAddress{{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"}, {Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.2"}}
+
+	r.updateConflicts([]*leases.ReportItem{a, cloned, b}, false)
+	require.Len(t, r.machineReports([]*leases.ReportItem{a, cloned, b}, false), 1)
+
+	// the conflict is published once and kept by partial cycles which contain a single candidate
+	r.updateConflicts([]*leases.ReportItem{a, cloned, b}, false)
+	r.updateConflicts([]*leases.ReportItem{a}, true)
+	require.Empty(t, r.machineReports([]*leases.ReportItem{a}, true))
+
+	// the cloned bmc could not be read, it still might report the same uuid
+	unread := &leases.ReportItem{Lease: cloned.Lease}
+	r.updateConflicts([]*leases.ReportItem{a, unread, b}, false)
+	require.Contains(t, r.conflicts, "a")
+
+	r.updateConflicts([]*leases.ReportItem{a, b}, false)
+	require.Empty(t, r.conflicts)
+	require.Len(t, r.machineReports([]*leases.ReportItem{a, b}, false), 2)
+
+	// a partial cycle leaves out machine uuids whose bmc was another one in the cycles of all devices
+	r.bmcMacs.set("a", cloned.Lease.Mac, false)
+	require.Empty(t, r.machineReports([]*leases.ReportItem{a}, true))
+	require.Len(t, r.machineReports([]*leases.ReportItem{a}, false), 1)
+
+	want := []Event{
+		{Type: EventUUIDConflict, UUID: "a", Candidates: candidates},
+		{Type: EventUUIDConflictResolved, UUID: "a", Candidates: candidates[:1]},
+	}
+	if diff := cmp.Diff(want, publisher.events, cmpopts.IgnoreFields(Event{}, "Time")); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
@@ -0,0 +1,158 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/metal-stack/metal-bmc/internal/address"
+	"github.com/metal-stack/metal-bmc/internal/credentials"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+)
+
+const (
+	defaultCredentialSet = "default"
+	// staticCredentialSet contains the credentials of a device of the static inventory
+	staticCredentialSet = "static"
+)
+
+// enrich reads the bmc details of the given item. All applicable credential sets are tried in order
+// until a connection could be established, the set which worked is tried first on the next cycle.
+// Credentials of a provisioned bmc user are always tried first, followed by the credentials of a static device.
+// If provisioning is enabled a new user is created on bmcs which were accessed with a credential set.
+func (r *reporter) enrich(item *leases.ReportItem) error {
+	// commands and console sessions connect to the address known by the metal-api, the usage is tracked by the lease ip
+	r.usage.SetAddress(item.Lease.Mac, item.Lease.Ip)
+
+	candidates := r.credentials.Candidates(item.Lease.Mac, item.Lease.Ip)
+	if d, ok := r.static.Device(item.Lease.Mac); ok && d.User != "" {
+		candidates = slices.Insert(candidates, 0, credentials.Set{Name: staticCredentialSet, User: d.User, Password: d.Password})
+	}
+	var (
+		provisioned *credentials.Credential
+		secretsErr  error
+	)
+	if r.secrets != nil {
+		provisioned, secretsErr = r.secrets.Get(item.Lease.Mac)
+		if secretsErr != nil {
+			r.log.Error("unable to read provisioned bmc credentials", "mac", item.Lease.Mac, "error", secretsErr)
+		}
+		if provisioned != nil {
+			sets := credentials.Sets{{Name: provisionedCredentialSet, User: provisioned.User, Password: provisioned.Password}}
+			if provisioned.PreviousPassword != "" {
+				// a rotation was interrupted, the bmc accepts either of both passwords
+				sets = append(sets, credentials.Set{Name: previousCredentialSet, User: provisioned.User, Password: provisioned.PreviousPassword})
+			}
+			candidates = slices.Insert(candidates, 0, sets...)
+		}
+	}
+	if len(candidates) == 0 {
+		return &leases.EnrichmentError{Stage: leases.StageConnect, Err: fmt.Errorf("no applicable credentials")}
+	}
+
+	var errs []error
+	for _, set := range candidates {
+		err := item.EnrichWithBMCDetails(r.log, r.cfg.IpmiPort, set.User, set.Password)
+		if err == nil {
+			r.credentials.Remember(item.Lease.Mac, set.Name, manufacturer(item))
+			// sensors, logs and the inventory are read before the credentials are maintained, a rotation changes the password
+			if r.cfg.CollectSensors {
+				r.collectSensors(item, set)
+			}
+			if r.cfg.CollectLogs && item.UUID != nil {
+				r.collectLogs(item, set)
+			}
+			if r.cfg.CollectInventory && item.UUID != nil {
+				r.collectInventory(item, set)
+			}
+			r.maintainCredentials(item, set, provisioned, secretsErr)
+			return nil
+		}
+
+		var enrichmentErr *leases.EnrichmentError
+		if !errors.As(err, &enrichmentErr) || enrichmentErr.Stage != leases.StageConnect || isUnreachable(err) {
+			// only connection failures caused by wrong credentials are worth trying the next set
+			return err
+		}
+
+		r.log.Debug("unable to connect to bmc with credential set", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "set", set.Name, "error", err)
+		errs = append(errs, fmt.Errorf("credential set %q: %w", set.Name, enrichmentErr.Err))
+	}
+
+	return &leases.EnrichmentError{Stage: leases.StageConnect, Err: errors.Join(errs...)}
+}
+
+// maintainCredentials provisions, settles or rotates the bmc user of the given item after its details were read with the given set.
+// No user is provisioned if the provisioned credentials could not be read, the bmc probably has a provisioned user already.
+func (r *reporter) maintainCredentials(item *leases.ReportItem, set credentials.Set, provisioned *credentials.Credential, secretsErr error) {
+	switch set.Name {
+	case provisionedCredentialSet, previousCredentialSet:
+		err := r.settleRotation(item, set, provisioned)
+		if err != nil {
+			item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageRotation, Err: err})
+			return
+		}
+		if r.cfg.IpmiPasswordRotationInterval <= 0 || set.Name == previousCredentialSet {
+			return
+		}
+		// the settled credentials differ from the ones the bmc was accessed with
+		current, err := r.secrets.Get(item.Lease.Mac)
+		if err != nil {
+			item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageRotation, Err: fmt.Errorf("unable to read provisioned credentials: %w", err)})
+			return
+		}
+		if current == nil || time.Since(current.ChangedAt()) < r.cfg.IpmiPasswordRotationInterval {
+			return
+		}
+		err = r.rotate(item, *current)
+		if err != nil {
+			item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageRotation, Err: err})
+		}
+	default:
+		if !r.cfg.IpmiUserProvisioning {
+			return
+		}
+		if secretsErr != nil {
+			item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageProvisioning, Err: fmt.Errorf("unable to read provisioned credentials: %w", secretsErr)})
+			return
+		}
+		// either the bmc is new or its provisioned user does not work anymore, e.g. after a factory reset
+		err := r.provision(item, set, provisioned)
+		if err != nil {
+			item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageProvisioning, Err: err})
+		}
+	}
+}
+
+// isUnreachable returns true if the error was caused by the network and not by the bmc.
+func isUnreachable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// redfishHost returns the host of the redfish api of the bmc of the given item for redfish.Connect.
+func (r *reporter) redfishHost(item *leases.ReportItem) (string, error) {
+	ap, err := item.Lease.AddrPort(r.cfg.IpmiPort)
+	if err != nil {
+		return "", err
+	}
+	host := address.FromAddrPort(ap)
+	if r.cfg.RedfishPort != 0 {
+		host.Port = r.cfg.RedfishPort
+		return host.String(), nil
+	}
+	return host.URLHost(), nil
+}
+
+func manufacturer(item *leases.ReportItem) string {
+	if item.FRU == nil {
+		return ""
+	}
+	if item.FRU.ProductManufacturer != "" {
+		return item.FRU.ProductManufacturer
+	}
+	return item.FRU.BoardMfg
+}
@@ -0,0 +1,87 @@
+package reporter
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+)
+
+// EventType is the type of an event published by the reporter
+type EventType string
+
+const (
+	// EventLogEntry is published for every new entry of a log of a bmc
+	EventLogEntry EventType = "log-entry"
+	// EventComponentAdded is published if a hardware component was found in a slot which was empty before
+	EventComponentAdded EventType = "component-added"
+	// EventComponentRemoved is published if a hardware component is not found anymore
+	EventComponentRemoved EventType = "component-removed"
+	// EventComponentReplaced is published if another hardware component was found in a slot
+	EventComponentReplaced EventType = "component-replaced"
+	// EventUUIDConflict is published if a machine uuid is reported by more than one bmc
+	EventUUIDConflict EventType = "uuid-conflict"
+	// EventUUIDConflictResolved is published if a conflicting machine uuid is reported by a single bmc again
+	EventUUIDConflictResolved EventType = "uuid-conflict-resolved"
+	// EventAddressChanged is published if the bmc of a machine got another mac or ip
+	EventAddressChanged EventType = "bmc-address-changed"
+	// EventMissing is published if the bmc of a known machine has no active lease for several cycles
+	EventMissing EventType = "bmc-missing"
+	// EventReappeared is published if the bmc of a missing machine has an active lease again
+	EventReappeared EventType = "bmc-reappeared"
+)
+
+// Event is published by the reporter to the event topic
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	UUID string    `json:"uuid,omitempty"`
+	Mac  string    `json:"mac,omitempty"`
+	Ip   string    `json:"ip,omitempty"`
+
+	// Log is the redfish path of the log service the entry was read from
+	Log      string            `json:"log,omitempty"`
+	LogEntry *redfish.LogEntry `json:"log_entry,omitempty"`
+
+	Component *redfish.Component `json:"component,omitempty"`
+	// PreviousComponent is only set if a component was replaced
+	PreviousComponent *redfish.Component `json:"previous_component,omitempty"`
+
+	// Candidates are the bmcs which report the same machine uuid
+	Candidates []BMCAddress `json:"candidates,omitempty"`
+
+	// PreviousAddress is the address of the bmc before it changed to the mac and ip of the event
+	PreviousAddress *BMCAddress `json:"previous_address,omitempty"`
+	// Flapping is set if the bmc address of the machine changed too often
+	Flapping bool `json:"flapping,omitempty"`
+}
+
+// BMCAddress identifies a bmc by its mac and ip
+type BMCAddress struct {
+	Mac string `json:"mac"`
+	Ip  string `json:"ip"`
+}
+
+// Publisher publishes messages to a topic
+type Publisher interface {
+	Publish(topic string, body []byte) error
+}
+
+// publish sends the event to the event topic, failures are only logged.
+func (r *reporter) publish(e Event) {
+	if r.publisher == nil {
+		return
+	}
+	e.Time = time.Now()
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		r.log.Error("unable to marshal event", "type", e.Type, "error", err)
+		return
+	}
+
+	err = r.publisher.Publish(r.cfg.EventTopic, body)
+	if err != nil {
+		r.log.Error("unable to publish event", "type", e.Type, "uuid", e.UUID, "error", err)
+	}
+}
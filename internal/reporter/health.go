@@ -0,0 +1,115 @@
+package reporter
+
+import (
+	"fmt"
+
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type healthState string
+
+const (
+	healthOK       healthState = "ok"
+	healthDegraded healthState = "degraded"
+	healthCritical healthState = "critical"
+)
+
+// healthValues maps a health state to the value of the health metrics
+var healthValues = map[healthState]float64{
+	healthOK:       0,
+	healthDegraded: 1,
+	healthCritical: 2,
+}
+
+// machineHealth is the overall health of a machine which is derived from the power supplies,
+// sensors and unacknowledged log entries read from its bmc
+type machineHealth struct {
+	State   healthState `json:"state"`
+	Reasons []string    `json:"reasons,omitempty"`
+}
+
+func (h *machineHealth) add(state healthState, reason string) {
+	if healthValues[state] > healthValues[h.State] {
+		h.State = state
+	}
+	h.Reasons = append(h.Reasons, reason)
+}
+
+// redfishHealth maps the health reported by a bmc to a health state, false is returned if it is unknown.
+func redfishHealth(health string) (healthState, bool) {
+	switch health {
+	case "OK":
+		return healthOK, true
+	case "Warning":
+		return healthDegraded, true
+	case "Critical":
+		return healthCritical, true
+	default:
+		return "", false
+	}
+}
+
+// rollupHealth derives the health of the machine of the given item.
+func rollupHealth(item *leases.ReportItem) *machineHealth {
+	h := &machineHealth{State: healthOK}
+
+	for idx, ps := range item.PowerSupplies {
+		if ps == nil || ps.Status == nil || ps.Status.Health == nil {
+			continue
+		}
+		if ps.Status.State != nil && *ps.Status.State == "Absent" {
+			continue
+		}
+		if state, ok := redfishHealth(*ps.Status.Health); ok && state != healthOK {
+			h.add(state, fmt.Sprintf("power supply %d health is %s", idx, *ps.Status.Health))
+		}
+	}
+
+	for _, s := range item.Sensors {
+		switch {
+		case s.UpperCritical != nil && s.Reading >= *s.UpperCritical:
+			h.add(healthCritical, fmt.Sprintf("%s sensor %q reading %g is above the critical threshold %g", s.Type, s.Name, s.Reading, *s.UpperCritical))
+			continue
+		case s.LowerCritical != nil && s.Reading <= *s.LowerCritical:
+			h.add(healthCritical, fmt.Sprintf("%s sensor %q reading %g is below the critical threshold %g", s.Type, s.Name, s.Reading, *s.LowerCritical))
+			continue
+		}
+		if state, ok := redfishHealth(s.Health); ok && state != healthOK {
+			h.add(state, fmt.Sprintf("%s sensor %q health is %s", s.Type, s.Name, s.Health))
+		}
+	}
+
+	for _, e := range item.LogAlerts {
+		if state, ok := redfishHealth(e.Severity); ok && state != healthOK {
+			h.add(state, fmt.Sprintf("log entry %s: %s", e.ID, logEntryMessage(e)))
+		}
+	}
+
+	return h
+}
+
+func logEntryMessage(e redfish.LogEntry) string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.MessageID
+}
+
+// updateHealthMetrics replaces the health metrics with the health of the machines of the current cycle,
+// a partial cycle only replaces the health of its machines.
+func updateHealthMetrics(results []deviceResult, partial bool) {
+	if !partial {
+		machineHealthState.Reset()
+	}
+	for _, result := range results {
+		if partial && result.UUID != "" {
+			machineHealthState.DeletePartialMatch(prometheus.Labels{"uuid": result.UUID})
+		}
+		if result.Health == nil || result.UUID == "" {
+			continue
+		}
+		machineHealthState.With(prometheus.Labels{"uuid": result.UUID, "bmc_ip": result.Ip}).Set(healthValues[result.Health.State])
+	}
+}
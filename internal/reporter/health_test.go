@@ -0,0 +1,75 @@
+package reporter
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+	"github.com/metal-stack/metal-go/api/models"
+)
+
+func Test_rollupHealth(t *testing.T) {
+	tests := []struct {
+		name string
+		item *leases.ReportItem
+		want *machineHealth
+	}{
+		{
+			name: "no data",
+			item: &leases.ReportItem{},
+			want: &machineHealth{State: healthOK},
+		},
+		{
+			name: "healthy",
+			item: &leases.ReportItem{
+				PowerSupplies: []*models.V1PowerSupply{
+					{Status: &models.V1PowerSupplyStatus{Health: new("OK"), State: new("Enabled")}},
+					{Status: &models.V1PowerSupplyStatus{Health: new("Critical"), State: new("Absent")}},
+				},
+				Sensors: []redfish.Sensor{
+					{Type: redfish.SensorTemperature, Name: "CPU1 Temp", Reading: 42, Health: "OK", UpperCritical: new(90.0)},
+				},
+				LogAlerts: []redfish.LogEntry{{ID: "1", Severity: "OK", Message: "Power on"}},
+			},
+			want: &machineHealth{State: healthOK},
+		},
+		{
+			name: "degraded",
+			item: &leases.ReportItem{
+				Sensors: []redfish.Sensor{
+					{Type: redfish.SensorFan, Name: "FAN1", Reading: 300, Health: "Warning"},
+				},
+				LogAlerts: []redfish.LogEntry{{ID: "7", Severity: "Warning", MessageID: "ECC.Correctable"}},
+			},
+			want: &machineHealth{State: healthDegraded, Reasons: []string{
+				`fan sensor "FAN1" health is Warning`,
+				"log entry 7: ECC.Correctable",
+			}},
+		},
+		{
+			name: "critical",
+			item: &leases.ReportItem{
+				PowerSupplies: []*models.V1PowerSupply{
+					{Status: &models.V1PowerSupplyStatus{Health: new("Warning"), State: new("Enabled")}},
+				},
+				Sensors: []redfish.Sensor{
+					{Type: redfish.SensorTemperature, Name: "CPU1 Temp", Reading: 95, Health: "OK", UpperCritical: new(90.0)},
+					{Type: redfish.SensorVoltage, Name: "12V", Reading: 10, LowerCritical: new(10.5)},
+				},
+			},
+			want: &machineHealth{State: healthCritical, Reasons: []string{
+				"power supply 0 health is Warning",
+				`temperature sensor "CPU1 Temp" reading 95 is above the critical threshold 90`,
+				`voltage sensor "12V" reading 10 is below the critical threshold 10.5`,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, rollupHealth(tt.item)); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package reporter
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/metal-stack/metal-bmc/internal/credentials"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+)
+
+// inventories contains the last hardware inventory per machine uuid
+type inventories struct {
+	lock        sync.RWMutex
+	inventories map[string]*redfish.Inventory
+}
+
+func newInventories() *inventories {
+	return &inventories{
+		inventories: make(map[string]*redfish.Inventory),
+	}
+}
+
+func (i *inventories) get(uuid string) *redfish.Inventory {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.inventories[uuid]
+}
+
+func (i *inventories) set(uuid string, inventory *redfish.Inventory) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.inventories[uuid] = inventory
+}
+
+// componentChange is a component which was added, removed or replaced between two cycles
+type componentChange struct {
+	Type      EventType
+	Component redfish.Component
+	// Previous is only set if the component was replaced
+	Previous *redfish.Component
+}
+
+// collectInventory reads the hardware inventory of the bmc of the given item and publishes an event for every
+// component which was added, removed or replaced since the last cycle. On the first contact only the inventory is remembered.
+// The previous components of kinds which could not be read are kept.
+func (r *reporter) collectInventory(item *leases.ReportItem, set credentials.Set) {
+	host, err := r.redfishHost(item)
+	if err != nil {
+		item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageInventory, Err: err})
+		return
+	}
+
+	c, err := redfish.Connect(r.log, host, set.User, set.Password, redfishTimeout)
+	if err != nil {
+		item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageInventory, Err: err})
+		return
+	}
+	defer c.Close()
+
+	inventory, err := c.Inventory()
+	if err != nil {
+		r.log.Warn("could not read inventory of device", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "err", err)
+		item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageInventory, Err: err})
+		return
+	}
+
+	if len(inventory.Failed) > 0 {
+		r.log.Warn("could not read all components of device, keeping the previous ones", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "failed", inventory.Failed)
+	}
+
+	uuid := *item.UUID
+	previous := r.inventories.get(uuid)
+	if previous != nil {
+		inventory = mergeInventory(previous, inventory)
+		for _, change := range diffInventory(previous, inventory) {
+			r.log.Info("hardware component changed", "uuid", uuid, "mac", item.Lease.Mac, "ip", item.Lease.Ip, "change", change.Type, "component", change.Component, "previous", change.Previous)
+			r.publish(Event{
+				Type:              change.Type,
+				UUID:              uuid,
+				Mac:               item.Lease.Mac,
+				Ip:                item.Lease.Ip,
+				Component:         &change.Component,
+				PreviousComponent: change.Previous,
+			})
+		}
+	}
+	r.inventories.set(uuid, inventory)
+}
+
+// mergeInventory returns the current inventory with the previous components of the kinds which could not be read.
+// Kinds which could not be read in both inventories stay failed.
+func mergeInventory(previous, current *redfish.Inventory) *redfish.Inventory {
+	if len(current.Failed) == 0 {
+		return current
+	}
+
+	merged := &redfish.Inventory{}
+	for _, c := range current.Components {
+		if !current.HasFailed(c.Kind) {
+			merged.Components = append(merged.Components, c)
+		}
+	}
+	for _, c := range previous.Components {
+		if current.HasFailed(c.Kind) {
+			merged.Components = append(merged.Components, c)
+		}
+	}
+	for _, kind := range current.Failed {
+		if previous.HasFailed(kind) {
+			merged.Failed = append(merged.Failed, kind)
+		}
+	}
+	slices.SortFunc(merged.Components, func(a, b redfish.Component) int {
+		return cmp.Compare(a.Key(), b.Key())
+	})
+	return merged
+}
+
+// diffInventory returns the components which were added, removed or replaced, changes of the firmware are ignored.
+// Components of kinds which could not be read in one of the inventories are not compared.
+func diffInventory(previous, current *redfish.Inventory) []componentChange {
+	compared := func(c redfish.Component) bool {
+		return !previous.HasFailed(c.Kind) && !current.HasFailed(c.Kind)
+	}
+
+	before := make(map[string]redfish.Component, len(previous.Components))
+	for _, c := range previous.Components {
+		before[c.Key()] = c
+	}
+	after := make(map[string]bool, len(current.Components))
+
+	var changes []componentChange
+	for _, c := range current.Components {
+		if !compared(c) {
+			continue
+		}
+		after[c.Key()] = true
+		old, ok := before[c.Key()]
+		switch {
+		case !ok:
+			changes = append(changes, componentChange{Type: EventComponentAdded, Component: c})
+		case !old.SameHardware(c):
+			changes = append(changes, componentChange{Type: EventComponentReplaced, Component: c, Previous: &old})
+		}
+	}
+	for _, c := range previous.Components {
+		if compared(c) && !after[c.Key()] {
+			changes = append(changes, componentChange{Type: EventComponentRemoved, Component: c})
+		}
+	}
+	return changes
+}
+
+// ServeInventory responds with the last hardware inventory of the machine with the uuid given in the path.
+func (r *reporter) ServeInventory(w http.ResponseWriter, req *http.Request) {
+	inventory := r.inventories.get(req.PathValue("uuid"))
+	if inventory == nil {
+		http.Error(w, "no inventory of this machine was collected", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(inventory)
+	if err != nil {
+		r.log.Error("unable to write inventory", "error", err)
+	}
+}
@@ -0,0 +1,125 @@
+package reporter
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_diffInventory(t *testing.T) {
+	cpu := redfish.Component{Kind: redfish.ComponentCPU, Slot: "CPU1", Model: "Xeon", SerialNumber: "1"}
+	dimm := redfish.Component{Kind: redfish.ComponentDIMM, Slot: "P1-DIMMA1", SerialNumber: "2", FirmwareVersion: "1.0"}
+	nic := redfish.Component{Kind: redfish.ComponentNIC, Slot: "1", MACAddress: "ac:1f:6b:35:ac:62"}
+
+	updatedDIMM := dimm
+	updatedDIMM.FirmwareVersion = "2.0"
+	replacedDIMM := dimm
+	replacedDIMM.SerialNumber = "3"
+	replacedNIC := nic
+	replacedNIC.MACAddress = "ac:1f:6b:35:ac:63"
+	drive := redfish.Component{Kind: redfish.ComponentDrive, Slot: "1/Disk.0", SerialNumber: "4"}
+
+	tests := []struct {
+		name     string
+		previous []redfish.Component
+		current  []redfish.Component
+		want     []componentChange
+	}{
+		{
+			name:     "unchanged except firmware",
+			previous: []redfish.Component{cpu, dimm, nic},
+			current:  []redfish.Component{cpu, updatedDIMM, nic},
+			want:     nil,
+		},
+		{
+			name:     "added, removed and replaced",
+			previous: []redfish.Component{cpu, dimm, nic},
+			current:  []redfish.Component{replacedDIMM, drive, replacedNIC},
+			want: []componentChange{
+				{Type: EventComponentReplaced, Component: replacedDIMM, Previous: &dimm},
+				{Type: EventComponentAdded, Component: drive},
+				{Type: EventComponentReplaced, Component: replacedNIC, Previous: &nic},
+				{Type: EventComponentRemoved, Component: cpu},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffInventory(&redfish.Inventory{Components: tt.previous}, &redfish.Inventory{Components: tt.current})
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_mergeInventory(t *testing.T) {
+	cpu := redfish.Component{Kind: redfish.ComponentCPU, Slot: "CPU1", SerialNumber: "1"}
+	dimm := redfish.Component{Kind: redfish.ComponentDIMM, Slot: "P1-DIMMA1", SerialNumber: "2"}
+	dimm2 := redfish.Component{Kind: redfish.ComponentDIMM, Slot: "P1-DIMMB1", SerialNumber: "3"}
+	nic := redfish.Component{Kind: redfish.ComponentNIC, Slot: "1", MACAddress: "ac:1f:6b:35:ac:62"}
+
+	tests := []struct {
+		name        string
+		previous    *redfish.Inventory
+		current     *redfish.Inventory
+		want        *redfish.Inventory
+		wantChanges []componentChange
+	}{
+		{
+			name:     "previous components of a failed kind are kept",
+			previous: &redfish.Inventory{Components: []redfish.Component{cpu, dimm, dimm2, nic}},
+			current:  &redfish.Inventory{Components: []redfish.Component{cpu, dimm, nic}, Failed: []redfish.ComponentKind{redfish.ComponentDIMM}},
+			want:     &redfish.Inventory{Components: []redfish.Component{cpu, dimm, dimm2, nic}},
+		},
+		{
+			name:     "kinds which failed before are not compared once they are read",
+			previous: &redfish.Inventory{Components: []redfish.Component{cpu}, Failed: []redfish.ComponentKind{redfish.ComponentDIMM, redfish.ComponentNIC}},
+			current:  &redfish.Inventory{Components: []redfish.Component{cpu, dimm, dimm2}, Failed: []redfish.ComponentKind{redfish.ComponentNIC}},
+			want:     &redfish.Inventory{Components: []redfish.Component{cpu, dimm, dimm2}, Failed: []redfish.ComponentKind{redfish.ComponentNIC}},
+		},
+		{
+			name:        "components of other kinds are compared",
+			previous:    &redfish.Inventory{Components: []redfish.Component{cpu, dimm, nic}},
+			current:     &redfish.Inventory{Components: []redfish.Component{dimm}, Failed: []redfish.ComponentKind{redfish.ComponentDIMM, redfish.ComponentNIC}},
+			want:        &redfish.Inventory{Components: []redfish.Component{dimm, nic}},
+			wantChanges: []componentChange{{Type: EventComponentRemoved, Component: cpu}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeInventory(tt.previous, tt.current)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+			if diff := cmp.Diff(tt.wantChanges, diffInventory(tt.previous, got)); diff != "" {
+				t.Errorf("changes diff = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_reporter_ServeInventory(t *testing.T) {
+	r := &reporter{
+		log:         slog.Default(),
+		inventories: newInventories(),
+	}
+	r.inventories.set("a", &redfish.Inventory{Components: []redfish.Component{{System: "1", Kind: redfish.ComponentCPU, Slot: "CPU1"}}})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/inventory/{uuid}", r.ServeInventory)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/inventory/a", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"components":[{"system":"1","kind":"cpu","slot":"CPU1"}]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/inventory/b", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
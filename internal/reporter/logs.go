@@ -0,0 +1,283 @@
+package reporter
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/metal-stack/metal-bmc/internal/credentials"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+)
+
+// logPositions remembers the id of the last entry of every log per machine uuid, the positions are persisted
+// to the given file so entries which were added during a restart are published
+type logPositions struct {
+	lock      sync.Mutex
+	path      string
+	positions map[string]map[string]string
+	changed   bool
+}
+
+// newLogPositions returns the positions which were persisted to the given file, no positions are persisted if path is empty.
+func newLogPositions(path string) (*logPositions, error) {
+	p := &logPositions{
+		path:      path,
+		positions: make(map[string]map[string]string),
+	}
+	if path == "" {
+		return p, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return p, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(data, &p.positions)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse log state file: %w", err)
+	}
+	return p, nil
+}
+
+// save persists the positions if they changed since the last call.
+func (p *logPositions) save() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.path == "" || !p.changed {
+		return nil
+	}
+
+	data, err := json.Marshal(p.positions)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(p.path, data)
+	if err != nil {
+		return err
+	}
+	p.changed = false
+	return nil
+}
+
+func (p *logPositions) get(uuid, log string) (string, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	last, ok := p.positions[uuid][log]
+	return last, ok
+}
+
+func (p *logPositions) set(uuid, log, last string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.positions[uuid] == nil {
+		p.positions[uuid] = make(map[string]string)
+	}
+	if current, ok := p.positions[uuid][log]; ok && current == last {
+		return
+	}
+	p.positions[uuid][log] = last
+	p.changed = true
+}
+
+// logAlert is a warning or critical log entry which was not acknowledged yet
+type logAlert struct {
+	entry redfish.LogEntry
+	since time.Time
+}
+
+// logAlerts keeps the warning and critical log entries per machine uuid and log until the log is cleared
+// by an operator or the entries expire, so the health of a machine does not recover after a single cycle
+type logAlerts struct {
+	lock   sync.Mutex
+	alerts map[string]map[string][]logAlert
+}
+
+func newLogAlerts() *logAlerts {
+	return &logAlerts{
+		alerts: make(map[string]map[string][]logAlert),
+	}
+}
+
+// add remembers the given entry if its severity is warning or critical.
+func (a *logAlerts) add(uuid, log string, e redfish.LogEntry, now time.Time) {
+	if state, ok := redfishHealth(e.Severity); !ok || state == healthOK {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.alerts[uuid] == nil {
+		a.alerts[uuid] = make(map[string][]logAlert)
+	}
+	a.alerts[uuid][log] = append(a.alerts[uuid][log], logAlert{entry: e, since: now})
+}
+
+// acknowledge forgets the entries of the given log.
+func (a *logAlerts) acknowledge(uuid, log string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.alerts[uuid], log)
+}
+
+// active returns the entries of the given machine which are younger than the given max age, older entries are forgotten.
+// Entries never expire if max age is not set.
+func (a *logAlerts) active(uuid string, now time.Time, maxAge time.Duration) []redfish.LogEntry {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var entries []redfish.LogEntry
+	for _, log := range slices.Sorted(maps.Keys(a.alerts[uuid])) {
+		alerts := a.alerts[uuid][log]
+		if maxAge > 0 {
+			alerts = slices.DeleteFunc(alerts, func(alert logAlert) bool {
+				return now.Sub(alert.since) > maxAge
+			})
+			a.alerts[uuid][log] = alerts
+		}
+		for _, alert := range alerts {
+			entries = append(entries, alert.entry)
+		}
+	}
+	return entries
+}
+
+// collectLogs reads the logs of the bmc of the given item and publishes all entries which were added
+// since the last cycle. On the first contact only the position of the logs is remembered.
+// The system event log is cleared if it is filled by the configured percentage and no entries were added since it was read.
+func (r *reporter) collectLogs(item *leases.ReportItem, set credentials.Set) {
+	host, err := r.redfishHost(item)
+	if err != nil {
+		item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageLogs, Err: err})
+		return
+	}
+
+	c, err := redfish.Connect(r.log, host, set.User, set.Password, redfishTimeout)
+	if err != nil {
+		item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageLogs, Err: err})
+		return
+	}
+	defer c.Close()
+
+	logs, err := c.Logs()
+	if err != nil {
+		r.log.Warn("could not read logs of device", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "err", err)
+		item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageLogs, Err: err})
+		return
+	}
+
+	uuid := *item.UUID
+	now := time.Now()
+	for _, l := range logs {
+		last, known := r.logPositions.get(uuid, l.ID)
+		if known && last != "" && compareLogEntryIDs(lastLogEntryID(l.Entries), last) < 0 {
+			// the log was cleared by an operator
+			r.logAlerts.acknowledge(uuid, l.ID)
+		}
+		for _, e := range newLogEntries(l.Entries, last, known) {
+			item.LogEntries = append(item.LogEntries, e)
+			r.logAlerts.add(uuid, l.ID, e, now)
+			r.log.Info("new bmc log entry", "uuid", uuid, "mac", item.Lease.Mac, "ip", item.Lease.Ip, "log", l.ID, "entry", e)
+			r.publish(Event{
+				Type:     EventLogEntry,
+				UUID:     uuid,
+				Mac:      item.Lease.Mac,
+				Ip:       item.Lease.Ip,
+				Log:      l.ID,
+				LogEntry: &e,
+			})
+		}
+
+		position := lastLogEntryID(l.Entries)
+		// entries of an unknown log were not published, they must not be cleared
+		if known && shouldClearLog(l, r.cfg.LogClearPercentage) {
+			err := r.clearLog(c, l.ID, position)
+			if errors.Is(err, errLogChanged) {
+				// the new entries are published on the next cycle
+				r.log.Info("system event log of device changed since it was read, not clearing it", "uuid", uuid, "log", l.ID)
+			} else if err != nil {
+				r.log.Warn("could not clear system event log of device", "uuid", uuid, "log", l.ID, "err", err)
+				item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageLogs, Err: err})
+			} else {
+				r.log.Info("cleared system event log of device", "uuid", uuid, "log", l.ID, "entries", len(l.Entries), "max", l.MaxEntries)
+				position = ""
+			}
+		}
+		r.logPositions.set(uuid, l.ID, position)
+	}
+	item.LogAlerts = r.logAlerts.active(uuid, now, r.cfg.LogAlertMaxAge)
+}
+
+// errLogChanged is returned if entries were added to a log which should be cleared
+var errLogChanged = errors.New("log changed since it was read")
+
+// clearLog clears the given log if its last entry is still the given one.
+func (r *reporter) clearLog(c *redfish.Client, id, last string) error {
+	entries, err := c.LogEntries(id)
+	if err != nil {
+		return err
+	}
+	if lastLogEntryID(entries) != last {
+		return errLogChanged
+	}
+	return c.ClearLog(id)
+}
+
+// newLogEntries returns the entries after the given last entry in ascending order. If the log is not known,
+// no entries are returned. If the log was cleared since the last cycle, all entries are returned.
+func newLogEntries(entries []redfish.LogEntry, last string, known bool) []redfish.LogEntry {
+	if !known {
+		return nil
+	}
+
+	sorted := slices.SortedFunc(slices.Values(entries), func(a, b redfish.LogEntry) int {
+		return compareLogEntryIDs(a.ID, b.ID)
+	})
+	if last == "" || len(sorted) == 0 || compareLogEntryIDs(sorted[len(sorted)-1].ID, last) < 0 {
+		return sorted
+	}
+
+	idx := slices.IndexFunc(sorted, func(e redfish.LogEntry) bool {
+		return compareLogEntryIDs(e.ID, last) > 0
+	})
+	if idx < 0 {
+		return nil
+	}
+	return sorted[idx:]
+}
+
+func lastLogEntryID(entries []redfish.LogEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	return slices.MaxFunc(entries, func(a, b redfish.LogEntry) int {
+		return compareLogEntryIDs(a.ID, b.ID)
+	}).ID
+}
+
+// compareLogEntryIDs compares numeric ids by their value, which is used by most bmcs, other ids lexically.
+func compareLogEntryIDs(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	if errA == nil && errB == nil {
+		return cmp.Compare(na, nb)
+	}
+	return cmp.Compare(a, b)
+}
+
+func shouldClearLog(l redfish.Log, percentage int) bool {
+	if percentage <= 0 || !l.SEL || l.MaxEntries <= 0 {
+		return false
+	}
+	return len(l.Entries)*100 >= l.MaxEntries*percentage
+}
@@ -0,0 +1,145 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newLogEntries(t *testing.T) {
+	entries := func(ids ...string) []redfish.LogEntry {
+		var es []redfish.LogEntry
+		for _, id := range ids {
+			es = append(es, redfish.LogEntry{ID: id})
+		}
+		return es
+	}
+
+	tests := []struct {
+		name    string
+		entries []redfish.LogEntry
+		last    string
+		known   bool
+		want    []redfish.LogEntry
+	}{
+		{
+			name:    "unknown log",
+			entries: entries("1", "2"),
+			want:    nil,
+		},
+		{
+			name:    "previously empty log",
+			entries: entries("2", "1"),
+			known:   true,
+			want:    entries("1", "2"),
+		},
+		{
+			name:    "new entries are sorted numerically",
+			entries: entries("10", "9", "8", "11"),
+			last:    "9",
+			known:   true,
+			want:    entries("10", "11"),
+		},
+		{
+			name:    "no new entries",
+			entries: entries("8", "9"),
+			last:    "9",
+			known:   true,
+			want:    nil,
+		},
+		{
+			name:    "log was cleared",
+			entries: entries("1", "2"),
+			last:    "9",
+			known:   true,
+			want:    entries("1", "2"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newLogEntries(tt.entries, tt.last, tt.known)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_shouldClearLog(t *testing.T) {
+	sel := redfish.Log{SEL: true, MaxEntries: 10, Entries: make([]redfish.LogEntry, 9)}
+
+	if !shouldClearLog(sel, 90) {
+		t.Errorf("sel filled by 90%% must be cleared")
+	}
+	if shouldClearLog(sel, 95) {
+		t.Errorf("sel filled by 90%% must not be cleared at 95%%")
+	}
+	if shouldClearLog(sel, 0) {
+		t.Errorf("clearing must be disabled")
+	}
+	sel.SEL = false
+	if shouldClearLog(sel, 90) {
+		t.Errorf("only the sel must be cleared")
+	}
+}
+
+func Test_logPositions_persistence(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "log-positions.json")
+
+	p, err := newLogPositions(stateFile)
+	require.NoError(t, err)
+	_, known := p.get("a", "sel")
+	require.False(t, known)
+
+	p.set("a", "sel", "9")
+	p.set("a", "event", "")
+	require.NoError(t, p.save())
+
+	p, err = newLogPositions(stateFile)
+	require.NoError(t, err)
+	last, known := p.get("a", "sel")
+	require.True(t, known)
+	require.Equal(t, "9", last)
+	last, known = p.get("a", "event")
+	require.True(t, known)
+	require.Empty(t, last)
+
+	// unchanged positions are not written again
+	require.NoError(t, os.Remove(stateFile))
+	p.set("a", "sel", "9")
+	require.NoError(t, p.save())
+	_, err = os.Stat(stateFile)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_logAlerts(t *testing.T) {
+	a := newLogAlerts()
+	now := time.Now()
+
+	a.add("a", "sel", redfish.LogEntry{ID: "1", Severity: "OK"}, now)
+	a.add("a", "sel", redfish.LogEntry{ID: "2", Severity: "Critical"}, now)
+	a.add("a", "event", redfish.LogEntry{ID: "3", Severity: "Warning"}, now.Add(time.Hour))
+	a.add("b", "sel", redfish.LogEntry{ID: "4", Severity: "Warning"}, now)
+
+	// alerts are kept across cycles
+	for range 2 {
+		if diff := cmp.Diff([]redfish.LogEntry{{ID: "3", Severity: "Warning"}, {ID: "2", Severity: "Critical"}}, a.active("a", now, 0)); diff != "" {
+			t.Errorf("diff = %s", diff)
+		}
+	}
+
+	// alerts expire after the max age
+	if diff := cmp.Diff([]redfish.LogEntry{{ID: "3", Severity: "Warning"}}, a.active("a", now.Add(90*time.Minute), time.Hour)); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	// alerts are acknowledged by clearing their log
+	a.acknowledge("a", "event")
+	require.Empty(t, a.active("a", now, 0))
+	require.Len(t, a.active("b", now, 0), 1)
+}
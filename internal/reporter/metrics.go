@@ -0,0 +1,111 @@
+package reporter
+
+import (
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "metal_bmc"
+	metricsSubsystem = "reporter"
+	sensorSubsystem  = "sensor"
+)
+
+// sensorLabels identify a sensor of a machine
+var sensorLabels = []string{"uuid", "bmc_ip", "manufacturer", "product", "chassis", "sensor"}
+
+var (
+	enrichmentFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "enrichment_failures_total",
+		Help:      "number of failed attempts to read bmc details of a device by the stage which failed, the failing devices are listed in the report summary",
+	}, []string{"stage"})
+
+	enrichmentSkipped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "enrichment_skipped_total",
+		Help:      "number of enrichments which were skipped because of a backoff after previous failures",
+	})
+
+	quarantinedDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "quarantined_device",
+		Help:      "devices which are quarantined because they did not answer too often in a row, the value is the number of failures",
+	}, []string{"mac", "ip"})
+
+	machineHealthState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "machine_health",
+		Help:      "health of a machine derived from its power supplies, sensors and log entries, 0 is ok, 1 is degraded and 2 is critical",
+	}, []string{"uuid", "bmc_ip"})
+
+	bmcAddressChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "bmc_address_changes_total",
+		Help:      "number of changes of the mac or ip of the bmc of a machine",
+	}, []string{"uuid"})
+
+	missingBMCs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "missing_bmc",
+		Help:      "bmcs of known machines which have no active lease anymore, the value is the number of cycles since the bmc was seen last",
+	}, []string{"uuid", "mac", "ip"})
+
+	sensorTemperature = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: sensorSubsystem,
+		Name:      "temperature_celsius",
+		Help:      "temperature reading of a sensor",
+	}, sensorLabels)
+
+	sensorFanSpeed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: sensorSubsystem,
+		Name:      "fan_speed",
+		Help:      "speed of a fan, either in RPM or percent as given by the unit label",
+	}, append(slices.Clone(sensorLabels), "unit"))
+
+	sensorVoltage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: sensorSubsystem,
+		Name:      "voltage_volts",
+		Help:      "voltage reading of a sensor",
+	}, sensorLabels)
+
+	sensorPower = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: sensorSubsystem,
+		Name:      "power_watts",
+		Help:      "power consumption reading of a sensor",
+	}, sensorLabels)
+
+	sensorHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: sensorSubsystem,
+		Name:      "health",
+		Help:      "health of a sensor as reported by the bmc, 0 is ok, 1 is warning and 2 is critical",
+	}, append(slices.Clone(sensorLabels), "type"))
+)
+
+func init() {
+	prometheus.MustRegister(
+		enrichmentFailures,
+		enrichmentSkipped,
+		quarantinedDevices,
+		machineHealthState,
+		bmcAddressChanges,
+		missingBMCs,
+		sensorTemperature,
+		sensorFanSpeed,
+		sensorVoltage,
+		sensorPower,
+		sensorHealth,
+	)
+}
@@ -0,0 +1,183 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-go/api/models"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	OutputJSON  = "json"
+	OutputTable = "table"
+)
+
+// OnceOptions control a single report cycle which is started from the command line
+type OnceOptions struct {
+	// Macs limits the cycle to the given mac addresses
+	Macs []string
+	// Cidrs limits the cycle to the given networks
+	Cidrs []netip.Prefix
+	// NoEnrich only lists the leases without connecting to the bmcs
+	NoEnrich bool
+	// Submit sends the reports to the metal-api
+	Submit bool
+	// Output is either json or table
+	Output string
+}
+
+// Once collects and enriches the leases a single time and writes the resulting reports to the given writer,
+// they are only sent to the metal-api if requested. Bmc users are neither provisioned nor rotated and no events are published.
+func (r *reporter) Once(w io.Writer, opts OnceOptions) error {
+	if opts.Output != OutputJSON && opts.Output != OutputTable {
+		return fmt.Errorf("unsupported output %q, must be %s or %s", opts.Output, OutputJSON, OutputTable)
+	}
+	if opts.NoEnrich && opts.Submit {
+		return fmt.Errorf("reports can only be submitted if the leases are enriched")
+	}
+
+	cfg := *r.cfg
+	cfg.IpmiUserProvisioning = false
+	cfg.IpmiPasswordRotationInterval = 0
+	cfg.CollectLogs = false
+	cfg.CollectInventory = false
+	r.cfg = &cfg
+	r.publisher = nil
+
+	items, err := r.getReportItems()
+	if err != nil {
+		return fmt.Errorf("unable to retrieve report items: %w", err)
+	}
+	items = filterItems(items, opts.Macs, opts.Cidrs)
+
+	if opts.NoEnrich {
+		return writeLeases(w, items, opts.Output)
+	}
+
+	g := new(errgroup.Group)
+	// Allow 20 goroutines run in parallel at max
+	g.SetLimit(20)
+	errs := make([]error, len(items))
+	for idx, item := range items {
+		g.Go(func() error {
+			errs[idx] = r.enrich(item)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	reports := r.machineReports(items, false)
+	err = r.writeReports(w, items, errs, reports, opts.Output)
+	if err != nil {
+		return err
+	}
+
+	if !opts.Submit {
+		return nil
+	}
+	if len(reports) == 0 {
+		r.log.Info("no ipmi reports to submit")
+		return nil
+	}
+
+	result, err := r.sendBatches(reports)
+	if err != nil {
+		return err
+	}
+	r.log.Info("submitted ipmi reports", "# of reports", len(reports), "updated", result.updated, "created", result.created)
+	return nil
+}
+
+// filterItems returns the items which match any of the given macs and cidrs, no filter matches all items.
+func filterItems(items []*leases.ReportItem, macs []string, cidrs []netip.Prefix) []*leases.ReportItem {
+	var filtered []*leases.ReportItem
+	for _, item := range items {
+		if len(macs) > 0 && !slices.ContainsFunc(macs, func(mac string) bool {
+			return strings.EqualFold(mac, item.Lease.Mac)
+		}) {
+			continue
+		}
+		if len(cidrs) > 0 {
+			ip, err := netip.ParseAddr(item.Lease.Ip)
+			if err != nil || !slices.ContainsFunc(cidrs, func(cidr netip.Prefix) bool {
+				return cidr.Contains(ip)
+			}) {
+				continue
+			}
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered
+}
+
+func writeLeases(w io.Writer, items []*leases.ReportItem, output string) error {
+	ls := leases.Leases{}
+	for _, item := range items {
+		ls = append(ls, item.Lease)
+	}
+
+	if output == OutputJSON {
+		return writeJSON(w, ls)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "MAC\tIP\tBEGIN\tEND")
+	for _, l := range ls {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", l.Mac, l.Ip, l.Begin.Format(time.RFC3339), l.End.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func (r *reporter) writeReports(w io.Writer, items []*leases.ReportItem, errs []error, reports map[string]models.V1MachineIpmiReport, output string) error {
+	if output == OutputJSON {
+		return writeJSON(w, models.V1MachineIpmiReports{
+			Partitionid: r.cfg.PartitionID,
+			Reports:     reports,
+		})
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "MAC\tIP\tUUID\tBMC\tBIOS\tPOWER\tMANUFACTURER\tPRODUCT\tERRORS")
+	for idx, item := range items {
+		var problems []string
+		if errs[idx] != nil {
+			problems = append(problems, errs[idx].Error())
+		}
+		for _, e := range item.Errors {
+			problems = append(problems, e.Error())
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			item.Lease.Mac,
+			item.Lease.Ip,
+			deref(item.UUID),
+			deref(item.BmcVersion),
+			deref(item.BiosVersion),
+			deref(item.Powerstate),
+			manufacturer(item),
+			product(item),
+			strings.Join(problems, "; "),
+		)
+	}
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,85 @@
+package reporter
+
+import (
+	"bytes"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_filterItems(t *testing.T) {
+	items := []*leases.ReportItem{
+		{Lease: leases.Lease{Mac: "00:00:00:00:00:01", Ip: "10.0.0.1"}},
+		{Lease: leases.Lease{Mac: "00:00:00:00:00:02", Ip: "10.0.1.1"}},
+		{Lease: leases.Lease{Mac: "00:00:00:00:00:03", Ip: "2001:db8::1"}},
+	}
+
+	tests := []struct {
+		name  string
+		macs  []string
+		cidrs []netip.Prefix
+		want  []*leases.ReportItem
+	}{
+		{
+			name: "no filter",
+			want: items,
+		},
+		{
+			name: "by mac",
+			macs: []string{"00:00:00:00:00:0A", "00:00:00:00:00:02"},
+			want: items[1:2],
+		},
+		{
+			name:  "by cidr",
+			cidrs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24"), netip.MustParsePrefix("2001:db8::/64")},
+			want:  []*leases.ReportItem{items[0], items[2]},
+		},
+		{
+			name:  "by mac and cidr",
+			macs:  []string{"00:00:00:00:00:01"},
+			cidrs: []netip.Prefix{netip.MustParsePrefix("10.0.1.0/24")},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, filterItems(items, tt.macs, tt.cidrs)); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_reporter_Once_noEnrich(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dhcpd.leases")
+	require.NoError(t, os.WriteFile(path, []byte(leaseFile), 0600))
+
+	r := &reporter{
+		cfg: &config.Config{
+			LeaseFile:    path,
+			AllowedCidrs: []string{"10.0.0.1/24"},
+		},
+		log:    slog.Default(),
+		source: leases.NewISCSource(slog.Default(), path),
+	}
+
+	var out bytes.Buffer
+	err := r.Once(&out, OnceOptions{NoEnrich: true, Output: OutputTable})
+	require.NoError(t, err)
+	require.Equal(t, `MAC                IP        BEGIN                 END
+00:00:00:00:00:01  10.0.0.1  2080-01-08T14:44:02Z  2080-01-10T14:44:02Z
+`, out.String())
+
+	err = r.Once(&out, OnceOptions{NoEnrich: true, Submit: true, Output: OutputJSON})
+	require.EqualError(t, err, "reports can only be submitted if the leases are enriched")
+
+	err = r.Once(&out, OnceOptions{Output: "yaml"})
+	require.EqualError(t, err, `unsupported output "yaml", must be json or table`)
+}
@@ -0,0 +1,184 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/metal-stack/metal-go/api/models"
+)
+
+// pendingReport is a report which was collected but could not be sent to the metal-api
+type pendingReport struct {
+	CollectedAt time.Time                  `json:"collected_at"`
+	Report      models.V1MachineIpmiReport `json:"report"`
+}
+
+// pendingReports are the reports which could not be sent by machine uuid
+type pendingReports map[string]pendingReport
+
+// updatePending applies the given update to the pending reports of the state file, so the reports of machines which are
+// not part of the update are kept. Reports older than the max age are dropped, the file is removed if nothing is pending.
+func (r *reporter) updatePending(update func(p pendingReports)) error {
+	if r.cfg.ReportStateFile == "" {
+		return nil
+	}
+
+	p, err := r.loadPending()
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		p = pendingReports{}
+	}
+	update(p)
+	if r.cfg.ReportStateMaxAge > 0 {
+		maps.DeleteFunc(p, func(_ string, report pendingReport) bool {
+			return time.Since(report.CollectedAt) > r.cfg.ReportStateMaxAge
+		})
+	}
+
+	if len(p) == 0 {
+		err := os.Remove(r.cfg.ReportStateFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(r.cfg.ReportStateFile, data)
+}
+
+// persistPending replaces the pending reports of the collected machines with the reports of a cycle which could not be sent.
+func (r *reporter) persistPending(collected []string, failed map[string]models.V1MachineIpmiReport, collectedAt time.Time) error {
+	return r.updatePending(func(p pendingReports) {
+		for _, uuid := range collected {
+			delete(p, uuid)
+		}
+		for uuid, report := range failed {
+			p[uuid] = pendingReport{CollectedAt: collectedAt, Report: report}
+		}
+	})
+}
+
+// writeFileAtomic replaces the given file with the data, readers see either the old or the new content.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadPending reads the pending reports from the state file, nil is returned if there are none.
+func (r *reporter) loadPending() (pendingReports, error) {
+	if r.cfg.ReportStateFile == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(r.cfg.ReportStateFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var p pendingReports
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse report state file: %w", err)
+	}
+
+	return p, nil
+}
+
+// sendPersisted sends the reports which could not be sent before the last restart. It is called after the first
+// cycle, reports of machines which were collected again by that cycle are stale and dropped.
+// Reports which were replaced by the first cycle in the meantime are left untouched.
+func (r *reporter) sendPersisted(p pendingReports) error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	var (
+		reports = make(map[string]models.V1MachineIpmiReport)
+		done    []string
+	)
+	for uuid, pending := range p {
+		if age := time.Since(pending.CollectedAt); age > r.cfg.ReportStateMaxAge {
+			r.log.Info("discarding persisted ipmi report", "uuid", uuid, "collected at", pending.CollectedAt, "age", age.String())
+			done = append(done, uuid)
+			continue
+		}
+		if r.collected(uuid) {
+			r.log.Debug("dropping persisted ipmi report of machine which was collected again", "uuid", uuid)
+			done = append(done, uuid)
+			continue
+		}
+		reports[uuid] = pending.Report
+	}
+
+	var sendErr error
+	if len(reports) > 0 {
+		r.log.Info("sending persisted ipmi reports", "# of reports", len(reports))
+
+		var result *batchResult
+		result, sendErr = r.sendBatches(reports)
+		for uuid := range result.reported {
+			done = append(done, uuid)
+		}
+	}
+
+	err := r.updatePending(func(current pendingReports) {
+		for _, uuid := range done {
+			if c, ok := current[uuid]; ok && c.CollectedAt.Equal(p[uuid].CollectedAt) {
+				delete(current, uuid)
+			}
+		}
+	})
+	if err != nil {
+		return errors.Join(sendErr, err)
+	}
+
+	return sendErr
+}
+
+// collected returns true if the last cycle enriched the machine with the given uuid.
+func (r *reporter) collected(uuid string) bool {
+	r.summaryLock.RLock()
+	defer r.summaryLock.RUnlock()
+	if r.summary == nil {
+		return false
+	}
+	for _, result := range r.summary.Results {
+		if result.UUID == uuid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/metal-stack/metal-go/api/client/machine"
+	"github.com/metal-stack/metal-go/api/models"
+	"github.com/metal-stack/metal-go/test/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_reporter_persistPending(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "report.json")
+	r := &reporter{
+		cfg: &config.Config{
+			ReportStateFile:   stateFile,
+			ReportStateMaxAge: time.Hour,
+		},
+		log: slog.Default(),
+	}
+
+	got, err := r.loadPending()
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	first := time.Now().Add(-time.Minute).UTC()
+	err = r.persistPending([]string{"a", "b", "c"}, map[string]models.V1MachineIpmiReport{
+		"a": {BMCIP: new("10.0.0.1")},
+		"b": {BMCIP: new("10.0.0.2")},
+	}, first)
+	require.NoError(t, err)
+
+	// a newer cycle replaces the reports of the machines it collected and keeps the others
+	second := time.Now().UTC()
+	err = r.persistPending([]string{"a", "c"}, map[string]models.V1MachineIpmiReport{
+		"c": {BMCIP: new("10.0.0.3")},
+	}, second)
+	require.NoError(t, err)
+
+	got, err = r.loadPending()
+	require.NoError(t, err)
+	want := pendingReports{
+		"b": {CollectedAt: first, Report: models.V1MachineIpmiReport{BMCIP: new("10.0.0.2")}},
+		"c": {CollectedAt: second, Report: models.V1MachineIpmiReport{BMCIP: new("10.0.0.3")}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	// reports older than the max age are dropped
+	err = r.persistPending([]string{"c"}, map[string]models.V1MachineIpmiReport{
+		"d": {BMCIP: new("10.0.0.4")},
+	}, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+	got, err = r.loadPending()
+	require.NoError(t, err)
+	require.Equal(t, []string{"b"}, slices.Sorted(maps.Keys(got)))
+
+	err = r.persistPending([]string{"b"}, nil, time.Now())
+	require.NoError(t, err)
+	_, err = os.Stat(stateFile)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_reporter_sendPersisted(t *testing.T) {
+	tests := []struct {
+		name    string
+		pending pendingReports
+		summary *cycleSummary
+		// firstCycle are the reports which the first cycle persisted because they could not be sent
+		firstCycle map[string]models.V1MachineIpmiReport
+		mockFn     func(m *mock.Mock)
+		wantErr    string
+		wantState  []string
+	}{
+		{
+			name:    "recent reports are sent",
+			pending: pendingReports{"a": {CollectedAt: time.Now().Add(-time.Minute), Report: models.V1MachineIpmiReport{BMCIP: new("10.0.0.1")}}},
+			summary: &cycleSummary{Results: []deviceResult{{UUID: "b"}}},
+			mockFn: func(m *mock.Mock) {
+				m.On("IpmiReport", mock.Anything, nil).Return(&machine.IpmiReportOK{
+					Payload: &models.V1MachineIpmiReportResponse{Updated: []string{"a"}},
+				}, nil).Once()
+			},
+		},
+		{
+			name:    "old reports are discarded",
+			pending: pendingReports{"a": {CollectedAt: time.Now().Add(-2 * time.Hour), Report: models.V1MachineIpmiReport{BMCIP: new("10.0.0.1")}}},
+		},
+		{
+			name:    "reports of machines collected by the first cycle are dropped",
+			pending: pendingReports{"a": {CollectedAt: time.Now().Add(-time.Minute), Report: models.V1MachineIpmiReport{BMCIP: new("10.0.0.1")}}},
+			summary: &cycleSummary{Results: []deviceResult{{UUID: "a"}}},
+		},
+		{
+			name:       "pending reports of the first cycle are kept",
+			pending:    pendingReports{"a": {CollectedAt: time.Now().Add(-time.Minute), Report: models.V1MachineIpmiReport{BMCIP: new("10.0.0.1")}}},
+			summary:    &cycleSummary{Results: []deviceResult{{UUID: "a"}}},
+			firstCycle: map[string]models.V1MachineIpmiReport{"a": {BMCIP: new("10.0.0.2")}},
+			wantState:  []string{"a"},
+		},
+		{
+			name: "reports of machines which the first cycle did not collect are kept if they could not be sent",
+			pending: pendingReports{
+				"a": {CollectedAt: time.Now().Add(-time.Minute), Report: models.V1MachineIpmiReport{BMCIP: new("10.0.0.1")}},
+				"b": {CollectedAt: time.Now().Add(-time.Minute), Report: models.V1MachineIpmiReport{BMCIP: new("10.0.0.2")}},
+			},
+			summary:    &cycleSummary{Results: []deviceResult{{UUID: "a"}}},
+			firstCycle: map[string]models.V1MachineIpmiReport{"a": {BMCIP: new("10.0.0.3")}},
+			mockFn: func(m *mock.Mock) {
+				m.On("IpmiReport", mock.Anything, nil).Return(nil, errors.New("metal-api unavailable")).Once()
+			},
+			wantErr:   "metal-api unavailable",
+			wantState: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, metalClient := client.NewMetalMockClient(t, &client.MetalMockFns{
+				Machine: tt.mockFn,
+			})
+
+			stateFile := filepath.Join(t.TempDir(), "report.json")
+			r := &reporter{
+				cfg: &config.Config{
+					ReportStateFile:   stateFile,
+					ReportStateMaxAge: time.Hour,
+				},
+				log:     slog.Default(),
+				client:  metalClient,
+				summary: tt.summary,
+			}
+
+			data, err := json.Marshal(tt.pending)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(stateFile, data, 0600))
+
+			pending, err := r.loadPending()
+			require.NoError(t, err)
+
+			if tt.firstCycle != nil {
+				err = r.persistPending(slices.Collect(maps.Keys(tt.firstCycle)), tt.firstCycle, time.Now())
+				require.NoError(t, err)
+			}
+
+			err = r.sendPersisted(pending)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			state, err := r.loadPending()
+			require.NoError(t, err)
+			require.Equal(t, tt.wantState, slices.Sorted(maps.Keys(state)))
+			for uuid, report := range tt.firstCycle {
+				if diff := cmp.Diff(report, state[uuid].Report); diff != "" {
+					t.Errorf("report of the first cycle was replaced, diff = %s", diff)
+				}
+			}
+		})
+	}
+}
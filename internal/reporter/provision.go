@@ -0,0 +1,128 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/metal-stack/metal-bmc/internal/credentials"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+)
+
+const (
+	provisionedCredentialSet = "provisioned"
+	previousCredentialSet    = "provisioned-previous"
+	provisionedUserSuffixLen = 8
+	// ipmi limits passwords to 20 characters
+	provisionedPasswordLen = 20
+	redfishTimeout         = time.Minute
+)
+
+// provision creates a unique administrator user on the bmc of the given item with the credential set
+// which was accepted by the bmc. If the bmc still has the previously provisioned user, e.g. because its password
+// was changed by someone else, that user is reused with a new password. The user is verified before it is stored
+// in the secret backend, a created user is removed again from the bmc if either fails.
+// Afterwards all other provisioned users are removed from the bmc and the user of the credential set is disabled if configured.
+func (r *reporter) provision(item *leases.ReportItem, set credentials.Set, previous *credentials.Credential) error {
+	host, err := r.redfishHost(item)
+	if err != nil {
+		return err
+	}
+
+	password, err := credentials.GeneratePassword(provisionedPasswordLen)
+	if err != nil {
+		return fmt.Errorf("unable to generate password: %w", err)
+	}
+
+	c, err := redfish.Connect(r.log, host, set.User, set.Password, redfishTimeout)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	names, err := c.AccountNames()
+	if err != nil {
+		return fmt.Errorf("unable to read users: %w", err)
+	}
+
+	var (
+		user    string
+		created bool
+	)
+	if previous != nil && slices.Contains(names, previous.User) {
+		user = previous.User
+		err = c.ChangePassword(user, password)
+		if err != nil {
+			return fmt.Errorf("unable to reset password of user %s: %w", user, err)
+		}
+	} else {
+		user, err = credentials.GenerateUserName(r.cfg.IpmiUserPrefix, provisionedUserSuffixLen)
+		if err != nil {
+			return fmt.Errorf("unable to generate user name: %w", err)
+		}
+		err = c.CreateAdministrator(user, password)
+		if err != nil {
+			return fmt.Errorf("unable to create user %s: %w", user, err)
+		}
+		created = true
+	}
+
+	rollback := func(err error) error {
+		if !created {
+			// the password of a reused user is unknown already
+			return err
+		}
+		deleteErr := c.DeleteAccount(user)
+		if deleteErr != nil {
+			return errors.Join(err, fmt.Errorf("unable to remove user %s: %w", user, deleteErr))
+		}
+		return err
+	}
+
+	err = r.verify(host, user, password)
+	if err != nil {
+		return rollback(fmt.Errorf("unable to verify user %s: %w", user, err))
+	}
+
+	err = r.secrets.Put(item.Lease.Mac, credentials.Credential{User: user, Password: password, CreatedAt: time.Now()})
+	if err != nil {
+		return rollback(fmt.Errorf("unable to store credentials of user %s: %w", user, err))
+	}
+
+	r.log.Info("provisioned bmc user", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "user", user, "set", set.Name, "reused", !created)
+
+	// users of earlier provisionings are left over if the secret backend lost their credentials
+	for _, name := range names {
+		if name == user || !credentials.IsGeneratedUserName(name, r.cfg.IpmiUserPrefix, provisionedUserSuffixLen) {
+			continue
+		}
+		err = c.DeleteAccount(name)
+		if err != nil {
+			r.log.Warn("unable to remove stale provisioned bmc user", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "user", name, "error", err)
+			continue
+		}
+		r.log.Info("removed stale provisioned bmc user", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "user", name)
+	}
+
+	if r.cfg.IpmiDisableInitialUser && set.User != user {
+		err = c.DisableAccount(set.User)
+		if err != nil {
+			return fmt.Errorf("unable to disable user %s of credential set %s: %w", set.User, set.Name, err)
+		}
+		r.log.Info("disabled initial bmc user", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "user", set.User, "set", set.Name)
+	}
+
+	return nil
+}
+
+// verify checks that the bmc accepts the given credentials.
+func (r *reporter) verify(host, user, password string) error {
+	c, err := redfish.Connect(r.log, host, user, password, redfishTimeout)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.Verify()
+}
@@ -0,0 +1,351 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/metal-stack/metal-bmc/internal/credentials"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeAccountsPath = "/redfish/v1/AccountService/Accounts"
+
+type fakeAccount struct {
+	ID       string `json:"Id"`
+	UserName string `json:"UserName"`
+	Password string `json:"Password,omitempty"`
+	RoleID   string `json:"RoleId"`
+	Enabled  bool   `json:"Enabled"`
+}
+
+// fakeBMC is a minimal redfish api with basic and session authentication and an account service
+type fakeBMC struct {
+	lock     sync.Mutex
+	accounts map[string]*fakeAccount
+	sessions map[string]string
+	nextID   int
+	// ignorePasswords accepts password changes without applying them
+	ignorePasswords bool
+	server          *httptest.Server
+}
+
+func newFakeBMC(t *testing.T, accounts ...fakeAccount) *fakeBMC {
+	f := &fakeBMC{
+		accounts: make(map[string]*fakeAccount),
+		sessions: make(map[string]string),
+		nextID:   1,
+	}
+	for _, a := range accounts {
+		f.add(a)
+	}
+	f.server = httptest.NewTLSServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// lease returns a lease which points to the fake bmc
+func (f *fakeBMC) lease(t *testing.T) leases.Lease {
+	ap, err := netip.ParseAddrPort(strings.TrimPrefix(f.server.URL, "https://"))
+	require.NoError(t, err)
+	return leases.Lease{Mac: "ac:1f:6b:35:ac:62", Ip: ap.Addr().String(), Port: int(ap.Port())}
+}
+
+func (f *fakeBMC) add(a fakeAccount) *fakeAccount {
+	a.ID = fmt.Sprint(f.nextID)
+	f.nextID++
+	f.accounts[a.ID] = &a
+	return &a
+}
+
+// account returns a copy of the account with the given name, nil if there is none
+func (f *fakeBMC) account(userName string) *fakeAccount {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, a := range f.accounts {
+		if a.UserName == userName {
+			c := *a
+			return &c
+		}
+	}
+	return nil
+}
+
+func (f *fakeBMC) userNames() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	var names []string
+	for _, a := range f.accounts {
+		names = append(names, a.UserName)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (f *fakeBMC) authenticated(req *http.Request) bool {
+	if user, ok := f.sessions[req.Header.Get("X-Auth-Token")]; ok {
+		// sessions stay valid after password changes
+		for _, a := range f.accounts {
+			if a.UserName == user && a.Enabled {
+				return true
+			}
+		}
+		return false
+	}
+	user, password, ok := req.BasicAuth()
+	return ok && f.login(user, password) != nil
+}
+
+func (f *fakeBMC) login(user, password string) *fakeAccount {
+	for _, a := range f.accounts {
+		if a.UserName == user && a.Password == password && a.Enabled && password != "" {
+			return a
+		}
+	}
+	return nil
+}
+
+func (f *fakeBMC) serve(w http.ResponseWriter, req *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	switch {
+	case path == "/redfish/v1":
+		writeFakeJSON(w, http.StatusOK, map[string]any{
+			"@odata.id":      "/redfish/v1/",
+			"Id":             "RootService",
+			"AccountService": map[string]string{"@odata.id": "/redfish/v1/AccountService"},
+			"Links":          map[string]any{"Sessions": map[string]string{"@odata.id": "/redfish/v1/SessionService/Sessions"}},
+		})
+		return
+	case path == "/redfish/v1/SessionService/Sessions" && req.Method == http.MethodPost:
+		var body struct{ UserName, Password string }
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		if f.login(body.UserName, body.Password) == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		token := fmt.Sprintf("token-%d", len(f.sessions))
+		f.sessions[token] = body.UserName
+		w.Header().Set("X-Auth-Token", token)
+		w.Header().Set("Location", "/redfish/v1/SessionService/Sessions/"+token)
+		writeFakeJSON(w, http.StatusCreated, map[string]string{"Id": token})
+		return
+	}
+
+	if !f.authenticated(req) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(path, "/redfish/v1/SessionService/Sessions/") && req.Method == http.MethodDelete:
+		delete(f.sessions, strings.TrimPrefix(path, "/redfish/v1/SessionService/Sessions/"))
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/redfish/v1/AccountService":
+		writeFakeJSON(w, http.StatusOK, map[string]any{
+			"@odata.id": "/redfish/v1/AccountService",
+			"Id":        "AccountService",
+			"Accounts":  map[string]string{"@odata.id": fakeAccountsPath},
+		})
+	case path == fakeAccountsPath && req.Method == http.MethodGet:
+		var members []map[string]string
+		for _, a := range f.accounts {
+			members = append(members, map[string]string{"@odata.id": fakeAccountsPath + "/" + a.ID})
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]any{
+			"@odata.id":           fakeAccountsPath,
+			"Members":             members,
+			"Members@odata.count": len(members),
+		})
+	case path == fakeAccountsPath && req.Method == http.MethodPost:
+		var a fakeAccount
+		_ = json.NewDecoder(req.Body).Decode(&a)
+		if f.ignorePasswords {
+			a.Password = ""
+		}
+		created := f.add(a)
+		writeFakeJSON(w, http.StatusCreated, f.view(created))
+	case strings.HasPrefix(path, fakeAccountsPath+"/"):
+		a, ok := f.accounts[strings.TrimPrefix(path, fakeAccountsPath+"/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch req.Method {
+		case http.MethodGet:
+			writeFakeJSON(w, http.StatusOK, f.view(a))
+		case http.MethodPatch:
+			var patch map[string]any
+			_ = json.NewDecoder(req.Body).Decode(&patch)
+			if v, ok := patch["UserName"].(string); ok {
+				a.UserName = v
+			}
+			if v, ok := patch["Password"].(string); ok && !f.ignorePasswords {
+				a.Password = v
+			}
+			if v, ok := patch["Enabled"].(bool); ok {
+				a.Enabled = v
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			delete(f.accounts, a.ID)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeBMC) view(a *fakeAccount) map[string]any {
+	return map[string]any{
+		"@odata.id": fakeAccountsPath + "/" + a.ID,
+		"Id":        a.ID,
+		"UserName":  a.UserName,
+		"RoleId":    a.RoleID,
+		"Enabled":   a.Enabled,
+	}
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func Test_reporter_provision(t *testing.T) {
+	admin := fakeAccount{UserName: "ADMIN", Password: "ADMIN", RoleID: "Administrator", Enabled: true}
+	operator := fakeAccount{UserName: "operator", Password: "secret", RoleID: "Operator", Enabled: true}
+
+	tests := []struct {
+		name            string
+		accounts        []fakeAccount
+		previous        *credentials.Credential
+		disableInitial  bool
+		ignorePasswords bool
+		wantErr         string
+		// wantUser is the provisioned user, a generated user is expected if empty
+		wantUser     string
+		wantAccounts int
+		wantDisabled bool
+	}{
+		{
+			name:         "new bmc",
+			accounts:     []fakeAccount{admin, operator},
+			wantAccounts: 3,
+		},
+		{
+			name:         "previous user is reused",
+			accounts:     []fakeAccount{admin, {UserName: "metalabcd1234", Password: "changed", RoleID: "Administrator", Enabled: true}},
+			previous:     &credentials.Credential{User: "metalabcd1234", Password: "unknown"},
+			wantUser:     "metalabcd1234",
+			wantAccounts: 2,
+		},
+		{
+			name: "stale provisioned users are removed",
+			accounts: []fakeAccount{
+				admin,
+				operator,
+				{UserName: "metalabcd1234", Password: "lost", RoleID: "Administrator", Enabled: true},
+				{UserName: "metalefgh5678", Password: "lost", RoleID: "Administrator", Enabled: true},
+			},
+			wantAccounts: 3,
+		},
+		{
+			name:           "initial user is disabled",
+			accounts:       []fakeAccount{admin},
+			disableInitial: true,
+			wantAccounts:   2,
+			wantDisabled:   true,
+		},
+		{
+			name:            "created user is removed if it can not be verified",
+			accounts:        []fakeAccount{admin},
+			ignorePasswords: true,
+			wantErr:         "unable to verify user",
+			wantAccounts:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t, tt.accounts...)
+			bmc.ignorePasswords = tt.ignorePasswords
+
+			secrets, err := credentials.NewBackend(credentials.BackendFile, filepath.Join(t.TempDir(), "secrets.json"))
+			require.NoError(t, err)
+			r := &reporter{
+				cfg: &config.Config{
+					IpmiUserPrefix:         "metal",
+					IpmiDisableInitialUser: tt.disableInitial,
+					RedfishPort:            bmc.lease(t).Port,
+				},
+				log:     slog.Default(),
+				secrets: secrets,
+			}
+			item := &leases.ReportItem{Lease: bmc.lease(t)}
+
+			err = r.provision(item, credentials.Set{Name: defaultCredentialSet, User: "ADMIN", Password: "ADMIN"}, tt.previous)
+
+			assert.Len(t, bmc.userNames(), tt.wantAccounts)
+			got, getErr := secrets.Get(item.Lease.Mac)
+			require.NoError(t, getErr)
+
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				assert.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, got)
+
+			if tt.wantUser != "" {
+				assert.Equal(t, tt.wantUser, got.User)
+			} else {
+				assert.True(t, credentials.IsGeneratedUserName(got.User, "metal", provisionedUserSuffixLen))
+			}
+			account := bmc.account(got.User)
+			require.NotNil(t, account)
+			assert.Equal(t, got.Password, account.Password)
+			assert.True(t, account.Enabled)
+
+			assert.NotNil(t, bmc.account("ADMIN"))
+			assert.Equal(t, tt.wantDisabled, !bmc.account("ADMIN").Enabled)
+			if slices.Contains(tt.accounts, operator) {
+				assert.NotNil(t, bmc.account("operator"))
+			}
+		})
+	}
+}
+
+func Test_reporter_maintainCredentials_unreadableSecrets(t *testing.T) {
+	bmc := newFakeBMC(t, fakeAccount{UserName: "ADMIN", Password: "ADMIN", RoleID: "Administrator", Enabled: true})
+
+	r := &reporter{
+		cfg: &config.Config{
+			IpmiUserProvisioning: true,
+			IpmiUserPrefix:       "metal",
+			RedfishPort:          bmc.lease(t).Port,
+		},
+		log: slog.Default(),
+	}
+	item := &leases.ReportItem{Lease: bmc.lease(t)}
+
+	r.maintainCredentials(item, credentials.Set{Name: defaultCredentialSet, User: "ADMIN", Password: "ADMIN"}, nil, errors.New("permission denied"))
+
+	require.Len(t, item.Errors, 1)
+	require.EqualError(t, item.Errors[0], "provisioning: unable to read provisioned credentials: permission denied")
+	assert.Equal(t, []string{"ADMIN"}, bmc.userNames())
+}
@@ -1,19 +1,24 @@
 package reporter
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/netip"
 	"os"
 	"os/signal"
+	"reflect"
 	"slices"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/metal-stack/metal-bmc/internal/credentials"
 	"github.com/metal-stack/metal-bmc/internal/leases"
 	"github.com/metal-stack/metal-bmc/pkg/config"
 	metalgo "github.com/metal-stack/metal-go"
-	"github.com/metal-stack/metal-go/api/client/machine"
 	"github.com/metal-stack/metal-go/api/models"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
@@ -25,36 +30,232 @@ type reporter struct {
 	log    *slog.Logger
 	client metalgo.Client
 	sem    *semaphore.Weighted
+	source leases.LeaseSource
+	// static contains the bmcs with static addresses, nil if no static inventory is configured
+	static *leases.StaticInventory
+
+	// lastReports contains the last successfully reported state per machine uuid
+	lastReports map[string]models.V1MachineIpmiReport
+	// lastFullReport is the time of the last successful report of all machines
+	lastFullReport time.Time
+	// knownLeases contains the ip per bmc mac of the leases of the previous cycles
+	knownLeases map[string]string
+
+	backoff     *enrichmentBackoff
+	credentials *credentials.Store
+	// secrets contains the credentials of provisioned bmc users, nil if provisioning is disabled
+	secrets credentials.Backend
+	// usage coordinates password rotations with commands and console sessions
+	usage *credentials.Usage
+	// publisher publishes the events of the reporter
+	publisher    Publisher
+	logPositions *logPositions
+	logAlerts    *logAlerts
+	inventories  *inventories
+	// conflicts contains the bmcs per machine uuid which is reported by more than one bmc
+	conflicts map[string][]BMCAddress
+	// addresses contains the bmc address history per machine uuid
+	addresses map[string]*addressHistory
+
+	summaryLock sync.RWMutex
+	// summary of the last report cycle of all devices
+	summary *cycleSummary
+
+	// bmcMacs contains the bmc mac per machine uuid of all enriched devices
+	bmcMacs *bmcMacs
+
+	// triggers contains the requested on demand report cycles
+	triggers chan trigger
 }
 
 // New will create a reporter for MachineIpmiReports
-func New(log *slog.Logger, cfg *config.Config, client metalgo.Client) (*reporter, error) {
+func New(log *slog.Logger, cfg *config.Config, client metalgo.Client, secrets credentials.Backend, usage *credentials.Usage, publisher Publisher) (*reporter, error) {
+	var sets credentials.Sets
+	if cfg.IpmiCredentialsFile != "" {
+		var err error
+		sets, err = credentials.LoadFile(cfg.IpmiCredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the configured ipmi user and password are always tried last
+	sets = append(sets, credentials.Set{Name: defaultCredentialSet, User: cfg.IpmiUser, Password: cfg.IpmiPassword})
+	err := sets.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := newLeaseSource(log, cfg)
+	if err != nil {
+		return nil, err
+	}
+	positions, err := newLogPositions(cfg.LogStateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var static *leases.StaticInventory
+	if cfg.StaticInventoryFile != "" {
+		static, err = leases.NewStaticInventory(log, cfg.StaticInventoryFile)
+		if err != nil {
+			return nil, err
+		}
+		source = leases.NewMergedSource(log, source, static)
+	}
+
 	return &reporter{
 		cfg:    cfg,
 		log:    log,
 		client: client,
 		sem:    semaphore.NewWeighted(1),
+		source: source,
+		static: static,
+
+		lastReports: make(map[string]models.V1MachineIpmiReport),
+		knownLeases: make(map[string]string),
+		backoff:     newEnrichmentBackoff(cfg.EnrichmentBackoff, cfg.EnrichmentMaxBackoff, cfg.EnrichmentQuarantineAfter),
+		credentials: credentials.NewStore(sets),
+		secrets:     secrets,
+		usage:       usage,
+
+		publisher:    publisher,
+		logPositions: positions,
+		logAlerts:    newLogAlerts(),
+		inventories:  newInventories(),
+		conflicts:    make(map[string][]BMCAddress),
+		addresses:    make(map[string]*addressHistory),
+		bmcMacs:      newBMCMacs(),
+
+		triggers: make(chan trigger, triggerQueueSize),
 	}, nil
 }
 
-func (r reporter) Run() {
+// newLeaseSource returns the configured source of the dhcp leases.
+func newLeaseSource(log *slog.Logger, cfg *config.Config) (leases.LeaseSource, error) {
+	switch cfg.LeaseSource {
+	case leases.SourceISC:
+		return leases.NewISCSource(log, cfg.LeaseFile), nil
+	case leases.SourceKeaMemfile:
+		return leases.NewKeaMemfileSource(log, cfg.LeaseFile), nil
+	case leases.SourceDnsmasq:
+		return leases.NewDnsmasqSource(log, cfg.LeaseFile), nil
+	case leases.SourceKeaAPI:
+		if cfg.KeaAPIURL == "" {
+			return nil, fmt.Errorf("the kea-api lease source requires the url of the kea control agent")
+		}
+		return leases.NewKeaAPISource(log, leases.KeaAPIConfig{
+			URL:      cfg.KeaAPIURL,
+			User:     cfg.KeaAPIUser,
+			Password: cfg.KeaAPIPassword,
+			Timeout:  cfg.KeaAPITimeout,
+			Subnets:  cfg.KeaAPISubnets,
+			PageSize: cfg.KeaAPIPageSize,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported lease source %q", cfg.LeaseSource)
+	}
+}
+
+func (r *reporter) Run() {
+	done := make(chan struct{})
+	defer close(done)
+
+	var (
+		leaseFileChanges <-chan struct{}
+		err              error
+	)
+	if r.cfg.LeaseSource != leases.SourceKeaAPI {
+		leaseFileChanges, err = r.watchFile(done, r.cfg.LeaseFile)
+		if err != nil {
+			// only rely on the periodic reports
+			r.log.Error("unable to watch lease file", "error", err)
+		}
+	}
+	var inventoryChanges <-chan struct{}
+	if r.static != nil {
+		inventoryChanges, err = r.watchFile(done, r.cfg.StaticInventoryFile)
+		if err != nil {
+			// changes are picked up by the periodic reports
+			r.log.Error("unable to watch static inventory file", "error", err)
+		}
+	}
+
 	periodic := time.NewTicker(r.cfg.ReportInterval)
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	// the pending reports are loaded before the first cycle replaces them and only sent for machines which the first cycle did not collect
+	pending, err := r.loadPending()
+	if err != nil {
+		r.log.Error("unable to load persisted ipmi reports", "error", err)
+	}
+
+	r.runCollectAndReport(nil)
+
+	err = r.sendPersisted(pending)
+	if err != nil {
+		r.log.Error("unable to send persisted ipmi reports", "error", err)
+	}
+
 	for {
 		select {
 		case <-periodic.C:
-			err := r.collectAndReport()
-			if err != nil {
-				r.log.Error("collect and report", "error", err)
-			}
+			r.runCollectAndReport(nil)
+		case <-leaseFileChanges:
+			r.reportChangedLeases()
+		case <-inventoryChanges:
+			r.log.Info("static inventory changed, reporting leases")
+			r.runCollectAndReport(nil)
+		case t := <-r.triggers:
+			r.log.Info("running requested report cycle")
+			r.runCollectAndReport(&t)
 		case <-signals:
 			return
 		}
 	}
 }
 
-func (r reporter) collectAndReport() error {
+// reportChangedLeases runs a report cycle which is limited to the bmcs which got a lease or another ip since the previous cycles.
+// Dhcp servers rewrite the lease file with every renewal, all bmcs are only enriched by the periodic cycles.
+func (r *reporter) reportChangedLeases() {
+	items, err := r.getReportItems()
+	if err != nil {
+		r.log.Error("unable to retrieve report items", "error", err)
+		return
+	}
+
+	macs := r.changedLeases(items)
+	if len(macs) == 0 {
+		r.log.Debug("lease file changed, no lease of a bmc is new or changed")
+		return
+	}
+
+	r.log.Info("lease file changed, reporting new or changed leases", "macs", macs)
+	r.runCollectAndReport(&trigger{Macs: macs})
+}
+
+// changedLeases returns the macs of the items which had no lease or another ip in the previous cycles.
+func (r *reporter) changedLeases(items []*leases.ReportItem) []string {
+	var macs []string
+	for _, item := range items {
+		if ip, ok := r.knownLeases[item.Lease.Mac]; !ok || ip != item.Lease.Ip {
+			macs = append(macs, item.Lease.Mac)
+		}
+	}
+	slices.Sort(macs)
+	return macs
+}
+
+func (r *reporter) runCollectAndReport(t *trigger) {
+	err := r.collectAndReport(t)
+	if err != nil {
+		r.log.Error("collect and report", "error", err)
+	}
+}
+
+// collectAndReport runs a report cycle, it is limited to the devices of the given trigger if any.
+// Devices which were explicitly requested are enriched regardless of their backoff unless they are quarantined and always reported.
+func (r *reporter) collectAndReport(t *trigger) error {
 	if !r.sem.TryAcquire(1) {
 		r.log.Warn("lease reporting is still running")
 		return nil
@@ -68,31 +269,113 @@ func (r reporter) collectAndReport() error {
 		return fmt.Errorf("unable to retrieve report items: %w", err)
 	}
 
-	r.log.Info("reporting leases to metal-api", "count", len(items))
+	partial := !t.all()
+	if partial {
+		items = r.selectItems(items, t)
+		if len(items) == 0 {
+			r.log.Warn("no lease matches the requested devices", "macs", t.Macs, "ips", t.Ips, "uuids", t.UUIDs)
+			return nil
+		}
+	} else {
+		clear(r.knownLeases)
+	}
+	for _, item := range items {
+		r.knownLeases[item.Lease.Mac] = item.Lease.Ip
+	}
+
+	r.log.Info("reporting leases to metal-api", "count", len(items), "partial", partial)
 
 	g := new(errgroup.Group)
 	// Allow 20 goroutines run in parallel at max
 	g.SetLimit(20)
-	for _, item := range items {
+	now := time.Now()
+	results := make([]deviceResult, len(items))
+	for idx, item := range items {
+		// requested devices bypass the backoff, quarantined devices are only retried with the maximum backoff
+		if (!partial || r.backoff.isQuarantined(item.Lease.Mac)) && !r.backoff.shouldAttempt(item.Lease.Mac, now) {
+			if partial {
+				r.log.Warn("requested device is quarantined, skipping it until its next attempt", "mac", item.Lease.Mac, "ip", item.Lease.Ip)
+			}
+			results[idx] = newDeviceResult(item, true, nil)
+			continue
+		}
 		g.Go(func() error {
-			return item.EnrichWithBMCDetails(r.log, r.cfg.IpmiPort, r.cfg.IpmiUser, r.cfg.IpmiPassword)
+			err := r.enrich(item)
+			results[idx] = newDeviceResult(item, false, err)
+			if err != nil {
+				next := r.backoff.failure(item.Lease, err, time.Now())
+				enrichmentFailures.WithLabelValues(failedStage(err)).Inc()
+				r.log.Debug("backing off enrichment of device", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "next attempt", next)
+				return nil
+			}
+			r.backoff.success(item.Lease.Mac)
+			if item.UUID != nil {
+				r.bmcMacs.set(*item.UUID, item.Lease.Mac, partial)
+			}
+			return nil
 		})
 	}
-	err = g.Wait()
+	_ = g.Wait()
+
+	err = r.logPositions.save()
 	if err != nil {
-		r.log.Error("could not enrich all ipmi details", "error", err)
+		r.log.Error("unable to persist log positions", "error", err)
+	}
+
+	summary := newCycleSummary(start, results)
+	if summary.Skipped > 0 {
+		enrichmentSkipped.Add(float64(summary.Skipped))
+	}
+	r.updateQuarantine()
+	r.updateSensorMetrics(items, partial)
+	updateHealthMetrics(summary.Results, partial)
+	r.updateConflicts(items, partial)
+	r.trackAddresses(items, time.Now())
+	if !partial {
+		r.detectMissing(summary.Results)
 	}
 
-	err = r.report(items)
+	err = r.report(items, start, partial)
+	summary.Duration = time.Since(start).String()
+	if err != nil {
+		summary.ReportErr = err.Error()
+	}
+	r.logSummary(summary)
+	if !partial {
+		r.setSummary(summary)
+	}
 	if err != nil {
 		return fmt.Errorf("could not report ipmi addresses %w", err)
 	}
-	r.log.Info("reporting leases to metal-api", "took", time.Since(start).String())
+	r.log.Info("reporting leases to metal-api", "took", summary.Duration)
 	return nil
 }
 
-func (r reporter) getReportItems() ([]*leases.ReportItem, error) {
-	ls, err := leases.ReadLeases(r.log, r.cfg.LeaseFile)
+// failedStage returns the stage in which the enrichment failed with the given error.
+func failedStage(err error) string {
+	var enrichmentErr *leases.EnrichmentError
+	if errors.As(err, &enrichmentErr) {
+		return string(enrichmentErr.Stage)
+	}
+	return "unknown"
+}
+
+// updateQuarantine logs the quarantined devices and updates the corresponding metric.
+func (r *reporter) updateQuarantine() {
+	quarantined := r.backoff.quarantined()
+
+	quarantinedDevices.Reset()
+	for _, d := range quarantined {
+		quarantinedDevices.WithLabelValues(d.Mac, d.Ip).Set(float64(d.Failures))
+	}
+
+	if len(quarantined) > 0 {
+		r.log.Warn("devices are quarantined because they did not answer", "count", len(quarantined), "devices", quarantined)
+	}
+}
+
+func (r *reporter) getReportItems() ([]*leases.ReportItem, error) {
+	ls, err := r.source.Leases()
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +409,7 @@ func (r reporter) getReportItems() ([]*leases.ReportItem, error) {
 	return items, nil
 }
 
-func (r reporter) isInAllowedCidr(ip string) bool {
+func (r *reporter) isInAllowedCidr(ip string) bool {
 	parsedIP, err := netip.ParseAddr(ip)
 	if err != nil {
 		r.log.Error("given ip is not parsable", "ip", ip, "error", err)
@@ -144,19 +427,86 @@ func (r reporter) isInAllowedCidr(ip string) bool {
 	return false
 }
 
-// report will send all gathered information about machines to the metal-api
-func (r reporter) report(items []*leases.ReportItem) error {
-	partitionID := r.cfg.PartitionID
+// report will send the gathered information about machines to the metal-api, only new or changed
+// reports are sent unless the full report interval has passed since the last full report.
+// Reports which could not be sent are persisted and replace the pending reports of older cycles for the same machines.
+// The reports of a partial cycle are always sent, they are not persisted because the next cycle reports them again.
+func (r *reporter) report(items []*leases.ReportItem, collectedAt time.Time, partial bool) error {
+	reports := r.machineReports(items, partial)
+
+	full := !partial && time.Since(r.lastFullReport) >= r.cfg.FullReportInterval
+	toReport := reports
+	if partial && len(toReport) == 0 {
+		r.log.Info("no ipmi information of the requested devices, skipping report")
+		return nil
+	}
+	if !full && !partial {
+		toReport = r.changedReports(reports)
+		if len(toReport) == 0 {
+			r.log.Info("no ipmi information changed since last report, skipping report", "# of machines", len(reports))
+			return r.persistPending(slices.Collect(maps.Keys(reports)), nil, collectedAt)
+		}
+	}
+
+	r.log.Info("sending ipmi reports", "full", full, "partial", partial, "# of reports", len(toReport), "# of machines", len(reports))
+
+	result, err := r.sendBatches(toReport)
+
+	if !partial {
+		failed := make(map[string]models.V1MachineIpmiReport)
+		for uuid, report := range toReport {
+			if !result.reported[uuid] {
+				failed[uuid] = report
+			}
+		}
+		if perr := r.persistPending(slices.Collect(maps.Keys(reports)), failed, collectedAt); perr != nil {
+			r.log.Error("unable to persist pending ipmi reports", "error", perr)
+		}
+	}
+
+	if full && err == nil {
+		r.lastReports = reports
+		r.lastFullReport = time.Now()
+	} else {
+		for uuid := range result.reported {
+			r.lastReports[uuid] = toReport[uuid]
+		}
+	}
+
+	r.log.Info("updated ipmi information", "# of machines", len(result.updated))
+	for _, u := range result.updated {
+		r.log.Info("ipmi information was updated for machine", "uuid", u)
+	}
+	for _, u := range result.created {
+		r.log.Info("ipmi information was set and machine was created", "uuid", u)
+	}
+
+	return err
+}
+
+// machineReports returns the reports of all items whose uuid is known by machine uuid.
+// Machine uuids which are reported by more than one bmc are left out. A partial cycle does not see all candidates,
+// it also leaves out machine uuids whose bmc was another one in the cycles of all devices.
+func (r *reporter) machineReports(items []*leases.ReportItem, partial bool) map[string]models.V1MachineIpmiReport {
 	reports := make(map[string]models.V1MachineIpmiReport)
+	byUUID := bmcsByUUID(items)
 
 	for _, item := range items {
 		if item.UUID == nil {
 			r.log.Error("could not determine uuid of device", "mac", item.Lease.Mac, "ip", item.Lease.Ip)
 			continue
 		}
+		if _, ok := r.conflicts[*item.UUID]; ok || len(byUUID[*item.UUID]) > 1 {
+			r.log.Debug("leaving conflicting machine uuid out of the report", "uuid", *item.UUID, "mac", item.Lease.Mac, "ip", item.Lease.Ip)
+			continue
+		}
+		if last := r.bmcMacs.get(*item.UUID); partial && last != "" && !strings.EqualFold(last, item.Lease.Mac) {
+			r.log.Warn("machine uuid was reported by another bmc in the last cycle, leaving it out of the requested report", "uuid", *item.UUID, "mac", item.Lease.Mac, "ip", item.Lease.Ip, "last mac", last)
+			continue
+		}
 
 		report := models.V1MachineIpmiReport{
-			BMCIP:             &item.Lease.Ip,
+			BMCIP:             new(item.Lease.Ip),
 			BMCVersion:        item.BmcVersion,
 			BIOSVersion:       item.BiosVersion,
 			FRU:               item.FRU,
@@ -168,23 +518,24 @@ func (r reporter) report(items []*leases.ReportItem) error {
 		reports[*item.UUID] = report
 	}
 
-	mir := &models.V1MachineIpmiReports{
-		Partitionid: partitionID,
-		Reports:     reports,
-	}
-
-	ok, err := r.client.Machine().IpmiReport(machine.NewIpmiReportParams().WithBody(mir), nil)
-	if err != nil {
-		return err
-	}
+	return reports
+}
 
-	r.log.Info("updated ipmi information", "# of machines", len(ok.Payload.Updated))
-	for _, u := range ok.Payload.Updated {
-		r.log.Info("ipmi information was updated for machine", "uuid", u)
-	}
-	for _, u := range ok.Payload.Created {
-		r.log.Info("ipmi information was set and machine was created", "uuid", u)
+// changedReports returns the reports which are new or differ from the last successfully reported state.
+// The power readings change with every cycle, they are only sent with changes of other fields and with full reports.
+func (r *reporter) changedReports(reports map[string]models.V1MachineIpmiReport) map[string]models.V1MachineIpmiReport {
+	changed := make(map[string]models.V1MachineIpmiReport)
+	for uuid, report := range reports {
+		last, ok := r.lastReports[uuid]
+		if ok && reflect.DeepEqual(withoutPowerMetric(last), withoutPowerMetric(report)) {
+			continue
+		}
+		changed[uuid] = report
 	}
+	return changed
+}
 
-	return nil
+func withoutPowerMetric(report models.V1MachineIpmiReport) models.V1MachineIpmiReport {
+	report.PowerMetric = nil
+	return report
 }
@@ -1,6 +1,8 @@
 package reporter
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"testing"
@@ -11,6 +13,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/metal-stack/metal-bmc/internal/leases"
 	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/metal-stack/metal-go/api/models"
 	"github.com/stretchr/testify/require"
 )
 
@@ -54,7 +57,8 @@ func Test_reporter_getReportItems(t *testing.T) {
 					LeaseFile:    f.Name(),
 					AllowedCidrs: []string{"10.0.0.1/24"},
 				},
-				log: slog.Default(),
+				log:    slog.Default(),
+				source: leases.NewISCSource(slog.Default(), f.Name()),
 			}
 
 			got, err := r.getReportItems()
@@ -69,3 +73,77 @@ func Test_reporter_getReportItems(t *testing.T) {
 		})
 	}
 }
+
+func Test_reporter_changedReports(t *testing.T) {
+	var (
+		ip1 = "10.0.0.1"
+		ip2 = "10.0.0.2"
+		on  = "ON"
+		off = "OFF"
+	)
+
+	r := &reporter{
+		lastReports: map[string]models.V1MachineIpmiReport{
+			"a": {BMCIP: &ip1, PowerState: &on},
+			"b": {BMCIP: &ip2, PowerState: &on},
+			"d": {BMCIP: &ip2, PowerState: &on, PowerMetric: &models.V1PowerMetric{Averageconsumedwatts: new(float32(310))}},
+		},
+	}
+
+	got := r.changedReports(map[string]models.V1MachineIpmiReport{
+		"a": {BMCIP: &ip1, PowerState: &on},
+		"b": {BMCIP: &ip2, PowerState: &off, PowerMetric: &models.V1PowerMetric{Averageconsumedwatts: new(float32(290))}},
+		"c": {BMCIP: &ip1},
+		"d": {BMCIP: &ip2, PowerState: &on, PowerMetric: &models.V1PowerMetric{Averageconsumedwatts: new(float32(295))}},
+	})
+
+	want := map[string]models.V1MachineIpmiReport{
+		"b": {BMCIP: &ip2, PowerState: &off, PowerMetric: &models.V1PowerMetric{Averageconsumedwatts: new(float32(290))}},
+		"c": {BMCIP: &ip1},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
+
+func Test_reporter_machineReports(t *testing.T) {
+	r := &reporter{
+		log:       slog.Default(),
+		conflicts: make(map[string][]BMCAddress),
+		bmcMacs:   newBMCMacs(),
+	}
+
+	got := r.machineReports([]*leases.ReportItem{
+		{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1", Port: 6230}, UUID: new("a")},
+		{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:63", Ip: "fd00::10:623"}, UUID: new("b")},
+	}, false)
+
+	// the port is only used to connect to the bmc, the metal-api gets the plain ip
+	require.Equal(t, "10.0.0.1", *got["a"].BMCIP)
+	require.Equal(t, "fd00::10:623", *got["b"].BMCIP)
+}
+
+func Test_reporter_changedLeases(t *testing.T) {
+	r := &reporter{
+		knownLeases: map[string]string{
+			"ac:1f:6b:35:ac:62": "10.0.0.1",
+			"ac:1f:6b:35:ac:63": "10.0.0.2",
+			"ac:1f:6b:35:ac:64": "10.0.0.3",
+		},
+	}
+
+	got := r.changedLeases([]*leases.ReportItem{
+		{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"}},
+		// the lease got another ip
+		{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.4"}},
+		// a new bmc
+		{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:65", Ip: "10.0.0.5"}},
+	})
+	require.Equal(t, []string{"ac:1f:6b:35:ac:63", "ac:1f:6b:35:ac:65"}, got)
+}
+
+func Test_failedStage(t *testing.T) {
+	err := fmt.Errorf("unable to enrich: %w", &leases.EnrichmentError{Stage: leases.StageConnect, Err: errors.New("connection refused")})
+	require.Equal(t, "connect", failedStage(err))
+	require.Equal(t, "unknown", failedStage(errors.New("connection refused")))
+}
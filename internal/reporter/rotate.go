@@ -0,0 +1,94 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/metal-stack/metal-bmc/internal/credentials"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+)
+
+// rotate changes the password of the provisioned bmc user of the given item. The new password is stored
+// together with the current one before it is set, so both are known if the rotation is interrupted.
+// If the bmc does not accept the new password, the current password is restored.
+// The rotation is deferred to the next cycle if the bmc is in use by a command or a console session.
+func (r *reporter) rotate(item *leases.ReportItem, current credentials.Credential) error {
+	host, err := r.redfishHost(item)
+	if err != nil {
+		return err
+	}
+
+	release, ok := r.usage.TryAcquireExclusive(item.Lease.Ip)
+	if !ok {
+		r.log.Info("bmc is in use, deferring password rotation", "mac", item.Lease.Mac, "ip", item.Lease.Ip)
+		return nil
+	}
+	defer release()
+
+	password, err := credentials.GeneratePassword(provisionedPasswordLen)
+	if err != nil {
+		return fmt.Errorf("unable to generate password: %w", err)
+	}
+
+	// a session is used because it stays valid after the password change, which is required for a rollback
+	c, err := redfish.ConnectSession(r.log, host, current.User, current.Password, redfishTimeout)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	pending := current
+	pending.Password = password
+	pending.PreviousPassword = current.Password
+	err = r.secrets.Put(item.Lease.Mac, pending)
+	if err != nil {
+		return fmt.Errorf("unable to store credentials: %w", err)
+	}
+
+	err = c.ChangePassword(current.User, password)
+	if err != nil {
+		// both passwords are kept, the one which is accepted by the bmc is kept on the next cycle
+		return fmt.Errorf("unable to change password of user %s: %w", current.User, err)
+	}
+
+	err = r.verify(host, current.User, password)
+	if err != nil {
+		err = fmt.Errorf("unable to verify new password of user %s: %w", current.User, err)
+		rollbackErr := c.ChangePassword(current.User, current.Password)
+		if rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("unable to restore password of user %s: %w", current.User, rollbackErr))
+		}
+		return errors.Join(err, r.secrets.Put(item.Lease.Mac, current))
+	}
+
+	rotated := pending
+	rotated.PreviousPassword = ""
+	rotated.RotatedAt = time.Now()
+	err = r.secrets.Put(item.Lease.Mac, rotated)
+	if err != nil {
+		return fmt.Errorf("unable to store credentials: %w", err)
+	}
+
+	r.log.Info("rotated password of bmc user", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "user", current.User)
+	return nil
+}
+
+// settleRotation keeps the password which was accepted by the bmc if a previous rotation was interrupted.
+func (r *reporter) settleRotation(item *leases.ReportItem, set credentials.Set, provisioned *credentials.Credential) error {
+	if provisioned.PreviousPassword == "" {
+		return nil
+	}
+
+	settled := *provisioned
+	if set.Name == previousCredentialSet {
+		settled.Password = provisioned.PreviousPassword
+	} else {
+		settled.RotatedAt = time.Now()
+	}
+	settled.PreviousPassword = ""
+
+	r.log.Info("settled interrupted password rotation", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "user", provisioned.User, "set", set.Name)
+	return r.secrets.Put(item.Lease.Mac, settled)
+}
@@ -0,0 +1,180 @@
+package reporter
+
+import (
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/metal-stack/metal-bmc/internal/credentials"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_reporter_settleRotation(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	interrupted := &credentials.Credential{User: "metalabc", Password: "new", PreviousPassword: "old", CreatedAt: created}
+
+	tests := []struct {
+		name        string
+		provisioned *credentials.Credential
+		set         string
+		want        *credentials.Credential
+	}{
+		{
+			name:        "nothing to settle",
+			provisioned: &credentials.Credential{User: "metalabc", Password: "current", CreatedAt: created},
+			set:         provisionedCredentialSet,
+			want:        nil,
+		},
+		{
+			name:        "new password was accepted",
+			provisioned: interrupted,
+			set:         provisionedCredentialSet,
+			want:        &credentials.Credential{User: "metalabc", Password: "new", CreatedAt: created},
+		},
+		{
+			name:        "previous password was accepted",
+			provisioned: interrupted,
+			set:         previousCredentialSet,
+			want:        &credentials.Credential{User: "metalabc", Password: "old", CreatedAt: created},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets, err := credentials.NewBackend(credentials.BackendFile, filepath.Join(t.TempDir(), "secrets.json"))
+			require.NoError(t, err)
+			r := &reporter{
+				log:     slog.Default(),
+				secrets: secrets,
+			}
+			item := &leases.ReportItem{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"}}
+
+			err = r.settleRotation(item, credentials.Set{Name: tt.set}, tt.provisioned)
+			require.NoError(t, err)
+
+			got, err := secrets.Get(item.Lease.Mac)
+			require.NoError(t, err)
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(credentials.Credential{}, "RotatedAt")); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_reporter_rotate(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	current := credentials.Credential{User: "metalabcd1234", Password: "current", CreatedAt: created}
+
+	tests := []struct {
+		name            string
+		ignorePasswords bool
+		inUse           bool
+		wantErr         string
+		wantRotated     bool
+	}{
+		{
+			name:        "password is rotated",
+			wantRotated: true,
+		},
+		{
+			name:            "password is restored if the new one is not accepted",
+			ignorePasswords: true,
+			wantErr:         "unable to verify new password of user metalabcd1234",
+		},
+		{
+			name:  "rotation is deferred while the bmc is in use",
+			inUse: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bmc := newFakeBMC(t, fakeAccount{UserName: current.User, Password: current.Password, RoleID: "Administrator", Enabled: true})
+			bmc.ignorePasswords = tt.ignorePasswords
+
+			secrets, err := credentials.NewBackend(credentials.BackendFile, filepath.Join(t.TempDir(), "secrets.json"))
+			require.NoError(t, err)
+			r := &reporter{
+				cfg:     &config.Config{RedfishPort: bmc.lease(t).Port},
+				log:     slog.Default(),
+				secrets: secrets,
+				usage:   credentials.NewUsage(),
+			}
+			item := &leases.ReportItem{Lease: bmc.lease(t)}
+			err = secrets.Put(item.Lease.Mac, current)
+			require.NoError(t, err)
+
+			if tt.inUse {
+				release := r.usage.Acquire(item.Lease.Ip)
+				defer release()
+			}
+
+			err = r.rotate(item, current)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			got, err := secrets.Get(item.Lease.Mac)
+			require.NoError(t, err)
+			require.NotNil(t, got)
+			assert.Empty(t, got.PreviousPassword)
+			assert.Equal(t, got.Password, bmc.account(current.User).Password, "stored password must be accepted by the bmc")
+
+			if !tt.wantRotated {
+				if diff := cmp.Diff(&current, got); diff != "" {
+					t.Errorf("diff = %s", diff)
+				}
+				return
+			}
+			assert.NotEqual(t, current.Password, got.Password)
+			assert.False(t, got.RotatedAt.IsZero())
+		})
+	}
+}
+
+func Test_reporter_maintainCredentials_rotation(t *testing.T) {
+	created := time.Now().Add(-2 * time.Hour)
+	interrupted := &credentials.Credential{User: "metalabcd1234", Password: "new", PreviousPassword: "old", CreatedAt: created}
+
+	bmc := newFakeBMC(t, fakeAccount{UserName: interrupted.User, Password: "old", RoleID: "Administrator", Enabled: true})
+
+	secrets, err := credentials.NewBackend(credentials.BackendFile, filepath.Join(t.TempDir(), "secrets.json"))
+	require.NoError(t, err)
+	r := &reporter{
+		cfg: &config.Config{
+			IpmiPasswordRotationInterval: time.Hour,
+			RedfishPort:                  bmc.lease(t).Port,
+		},
+		log:     slog.Default(),
+		secrets: secrets,
+		usage:   credentials.NewUsage(),
+	}
+	item := &leases.ReportItem{Lease: bmc.lease(t)}
+	err = secrets.Put(item.Lease.Mac, *interrupted)
+	require.NoError(t, err)
+
+	// the bmc was accessed with the previous password, which is kept and not rotated in the same cycle
+	r.maintainCredentials(item, credentials.Set{Name: previousCredentialSet, User: interrupted.User, Password: "old"}, interrupted, nil)
+	require.Empty(t, item.Errors)
+
+	got, err := secrets.Get(item.Lease.Mac)
+	require.NoError(t, err)
+	if diff := cmp.Diff(&credentials.Credential{User: interrupted.User, Password: "old", CreatedAt: created}, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	// on the next cycle the settled password is rotated
+	r.maintainCredentials(item, credentials.Set{Name: provisionedCredentialSet, User: got.User, Password: got.Password}, got, nil)
+	require.Empty(t, item.Errors)
+
+	rotated, err := secrets.Get(item.Lease.Mac)
+	require.NoError(t, err)
+	assert.NotEqual(t, "old", rotated.Password)
+	assert.Equal(t, rotated.Password, bmc.account(interrupted.User).Password)
+}
@@ -0,0 +1,95 @@
+package reporter
+
+import (
+	"github.com/metal-stack/metal-bmc/internal/credentials"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectSensors reads the sensors of the bmc of the given item over redfish, failures are added to the errors of the item.
+func (r *reporter) collectSensors(item *leases.ReportItem, set credentials.Set) {
+	host, err := r.redfishHost(item)
+	if err != nil {
+		item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageSensors, Err: err})
+		return
+	}
+
+	c, err := redfish.Connect(r.log, host, set.User, set.Password, redfishTimeout)
+	if err != nil {
+		r.log.Warn("could not establish redfish connection to device bmc", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "err", err)
+		item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageSensors, Err: err})
+		return
+	}
+	defer c.Close()
+
+	sensors, err := c.Sensors()
+	if err != nil {
+		r.log.Warn("could not read sensors of device", "mac", item.Lease.Mac, "ip", item.Lease.Ip, "err", err)
+		item.Errors = append(item.Errors, &leases.EnrichmentError{Stage: leases.StageSensors, Err: err})
+		return
+	}
+	item.Sensors = sensors
+}
+
+// updateSensorMetrics replaces the sensor metrics with the sensors read in the current cycle,
+// sensors of machines which could not be read in this cycle are removed. A partial cycle only replaces the sensors of its machines.
+func (r *reporter) updateSensorMetrics(items []*leases.ReportItem, partial bool) {
+	if !r.cfg.CollectSensors {
+		return
+	}
+
+	for _, vec := range []*prometheus.GaugeVec{sensorTemperature, sensorFanSpeed, sensorVoltage, sensorPower, sensorHealth} {
+		if !partial {
+			vec.Reset()
+			continue
+		}
+		for _, item := range items {
+			if item.UUID != nil {
+				vec.DeletePartialMatch(prometheus.Labels{"uuid": *item.UUID})
+			}
+		}
+	}
+
+	for _, item := range items {
+		if item.UUID == nil {
+			continue
+		}
+		manufacturer, product := manufacturer(item), product(item)
+		for _, s := range item.Sensors {
+			labels := prometheus.Labels{
+				"uuid":         *item.UUID,
+				"bmc_ip":       item.Lease.Ip,
+				"manufacturer": manufacturer,
+				"product":      product,
+				"chassis":      s.Chassis,
+				"sensor":       s.Name,
+			}
+
+			switch s.Type {
+			case redfish.SensorTemperature:
+				sensorTemperature.With(labels).Set(s.Reading)
+			case redfish.SensorFan:
+				sensorFanSpeed.MustCurryWith(prometheus.Labels{"unit": s.Unit}).With(labels).Set(s.Reading)
+			case redfish.SensorVoltage:
+				sensorVoltage.With(labels).Set(s.Reading)
+			case redfish.SensorPower:
+				sensorPower.With(labels).Set(s.Reading)
+			}
+
+			if state, ok := redfishHealth(s.Health); ok {
+				sensorHealth.MustCurryWith(prometheus.Labels{"type": string(s.Type)}).With(labels).Set(healthValues[state])
+			}
+		}
+	}
+}
+
+func product(item *leases.ReportItem) string {
+	if item.FRU == nil {
+		return ""
+	}
+	if item.FRU.ProductPartNumber != "" {
+		return item.FRU.ProductPartNumber
+	}
+	return item.FRU.BoardPartNumber
+}
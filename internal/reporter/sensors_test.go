@@ -0,0 +1,43 @@
+package reporter
+
+import (
+	"testing"
+
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-bmc/internal/redfish"
+	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/metal-stack/metal-go/api/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_reporter_updateSensorMetrics(t *testing.T) {
+	r := &reporter{
+		cfg: &config.Config{CollectSensors: true},
+	}
+
+	item := &leases.ReportItem{
+		Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"},
+		UUID:  new("a"),
+		FRU:   &models.V1MachineFru{ProductManufacturer: "Supermicro", ProductPartNumber: "SYS-2029BT"},
+		Sensors: []redfish.Sensor{
+			{Type: redfish.SensorTemperature, Chassis: "1", Name: "CPU1 Temp", Reading: 42, Health: "OK"},
+			{Type: redfish.SensorFan, Chassis: "1", Name: "FAN1", Reading: 3400, Unit: "RPM", Health: "Critical"},
+			{Type: redfish.SensorVoltage, Chassis: "1", Name: "12V", Reading: 12.1},
+			{Type: redfish.SensorPower, Chassis: "1", Name: "System Power Control", Reading: 230},
+		},
+	}
+	r.updateSensorMetrics([]*leases.ReportItem{item}, false)
+
+	labels := []string{"a", "10.0.0.1", "Supermicro", "SYS-2029BT", "1"}
+	assert.InDelta(t, 42, testutil.ToFloat64(sensorTemperature.WithLabelValues(append(labels, "CPU1 Temp")...)), 0.001)
+	assert.InDelta(t, 3400, testutil.ToFloat64(sensorFanSpeed.WithLabelValues(append(labels, "FAN1", "RPM")...)), 0.001)
+	assert.InDelta(t, 12.1, testutil.ToFloat64(sensorVoltage.WithLabelValues(append(labels, "12V")...)), 0.001)
+	assert.InDelta(t, 230, testutil.ToFloat64(sensorPower.WithLabelValues(append(labels, "System Power Control")...)), 0.001)
+	assert.InDelta(t, 2, testutil.ToFloat64(sensorHealth.WithLabelValues(append(labels, "FAN1", "fan")...)), 0.001)
+	assert.Equal(t, 2, testutil.CollectAndCount(sensorHealth))
+
+	// sensors of machines which were not read are removed
+	r.updateSensorMetrics(nil, false)
+	assert.Equal(t, 0, testutil.CollectAndCount(sensorTemperature))
+}
@@ -0,0 +1,161 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/metal-stack/metal-bmc/internal/leases"
+)
+
+type deviceStatus string
+
+const (
+	deviceStatusOK      deviceStatus = "ok"
+	deviceStatusPartial deviceStatus = "partial"
+	deviceStatusFailed  deviceStatus = "failed"
+	deviceStatusSkipped deviceStatus = "skipped"
+)
+
+// cycleSummary contains the results of a report cycle per device
+type cycleSummary struct {
+	Start     time.Time      `json:"start"`
+	Duration  string         `json:"duration"`
+	Devices   int            `json:"devices"`
+	OK        int            `json:"ok"`
+	Partial   int            `json:"partial"`
+	Failed    int            `json:"failed"`
+	Skipped   int            `json:"skipped"`
+	Degraded  int            `json:"degraded"`
+	Critical  int            `json:"critical"`
+	ReportErr string         `json:"report_error,omitempty"`
+	Results   []deviceResult `json:"results"`
+}
+
+// deviceResult is the result of enriching a single device
+type deviceResult struct {
+	Mac    string       `json:"mac"`
+	Ip     string       `json:"ip"`
+	UUID   string       `json:"uuid,omitempty"`
+	Status deviceStatus `json:"status"`
+	Errors []stageError `json:"errors,omitempty"`
+	// Health is only set if the details of the device could be read
+	Health *machineHealth `json:"health,omitempty"`
+}
+
+// stageError describes in which stage the enrichment of a device failed and why
+type stageError struct {
+	Stage leases.EnrichmentStage `json:"stage"`
+	Error string                 `json:"error"`
+}
+
+func newDeviceResult(item *leases.ReportItem, skipped bool, err error) deviceResult {
+	result := deviceResult{
+		Mac:    item.Lease.Mac,
+		Ip:     item.Lease.Ip,
+		Status: deviceStatusOK,
+	}
+	if item.UUID != nil {
+		result.UUID = *item.UUID
+	}
+
+	if skipped {
+		result.Status = deviceStatusSkipped
+		return result
+	}
+
+	for _, e := range item.Errors {
+		result.Status = deviceStatusPartial
+		result.Errors = append(result.Errors, stageError{Stage: e.Stage, Error: e.Err.Error()})
+	}
+
+	if err != nil {
+		result.Status = deviceStatusFailed
+		var enrichmentErr *leases.EnrichmentError
+		if errors.As(err, &enrichmentErr) {
+			result.Errors = append(result.Errors, stageError{Stage: enrichmentErr.Stage, Error: enrichmentErr.Err.Error()})
+		} else {
+			result.Errors = append(result.Errors, stageError{Error: err.Error()})
+		}
+		return result
+	}
+
+	result.Health = rollupHealth(item)
+	return result
+}
+
+func newCycleSummary(start time.Time, results []deviceResult) *cycleSummary {
+	s := &cycleSummary{
+		Start:   start,
+		Devices: len(results),
+		Results: results,
+	}
+
+	slices.SortFunc(s.Results, func(a, b deviceResult) int {
+		return strings.Compare(a.Mac, b.Mac)
+	})
+
+	for _, r := range results {
+		switch r.Status {
+		case deviceStatusOK:
+			s.OK++
+		case deviceStatusPartial:
+			s.Partial++
+		case deviceStatusFailed:
+			s.Failed++
+		case deviceStatusSkipped:
+			s.Skipped++
+		}
+
+		if r.Health != nil {
+			switch r.Health.State {
+			case healthDegraded:
+				s.Degraded++
+			case healthCritical:
+				s.Critical++
+			}
+		}
+	}
+
+	return s
+}
+
+// logSummary logs the summary of a cycle, every device which could not be enriched completely and every machine which is not healthy.
+func (r *reporter) logSummary(s *cycleSummary) {
+	for _, result := range s.Results {
+		for _, e := range result.Errors {
+			r.log.Warn("enrichment of device failed", "mac", result.Mac, "ip", result.Ip, "status", result.Status, "stage", e.Stage, "error", e.Error)
+		}
+		if result.Health != nil && result.Health.State != healthOK {
+			r.log.Warn("machine is not healthy", "uuid", result.UUID, "mac", result.Mac, "ip", result.Ip, "health", result.Health.State, "reasons", result.Health.Reasons)
+		}
+	}
+	r.log.Info("enrichment summary", "devices", s.Devices, "ok", s.OK, "partial", s.Partial, "failed", s.Failed, "skipped", s.Skipped, "degraded", s.Degraded, "critical", s.Critical, "took", s.Duration)
+}
+
+func (r *reporter) setSummary(s *cycleSummary) {
+	r.summaryLock.Lock()
+	defer r.summaryLock.Unlock()
+	r.summary = s
+}
+
+// ServeSummary responds with the summary of the last report cycle.
+func (r *reporter) ServeSummary(w http.ResponseWriter, _ *http.Request) {
+	r.summaryLock.RLock()
+	s := r.summary
+	r.summaryLock.RUnlock()
+
+	if s == nil {
+		http.Error(w, "no report cycle has finished yet", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(s)
+	if err != nil {
+		r.log.Error("unable to write summary", "error", err)
+	}
+}
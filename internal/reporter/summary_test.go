@@ -0,0 +1,59 @@
+package reporter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-go/api/models"
+)
+
+func Test_newCycleSummary(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	results := []deviceResult{
+		newDeviceResult(&leases.ReportItem{
+			Lease: leases.Lease{Mac: "00:00:00:00:00:04", Ip: "10.0.0.4"},
+		}, true, nil),
+		newDeviceResult(&leases.ReportItem{
+			Lease: leases.Lease{Mac: "00:00:00:00:00:03", Ip: "10.0.0.3"},
+		}, false, &leases.EnrichmentError{Stage: leases.StageConnect, Err: fmt.Errorf("connection refused")}),
+		newDeviceResult(&leases.ReportItem{
+			Lease:  leases.Lease{Mac: "00:00:00:00:00:02", Ip: "10.0.0.2"},
+			UUID:   new("uuid-2"),
+			Errors: []*leases.EnrichmentError{{Stage: leases.StagePowerState, Err: fmt.Errorf("timeout")}},
+			PowerSupplies: []*models.V1PowerSupply{
+				{Status: &models.V1PowerSupplyStatus{Health: new("Critical"), State: new("Enabled")}},
+			},
+		}, false, nil),
+		newDeviceResult(&leases.ReportItem{
+			Lease: leases.Lease{Mac: "00:00:00:00:00:01", Ip: "10.0.0.1"},
+			UUID:  new("uuid-1"),
+		}, false, nil),
+	}
+
+	want := &cycleSummary{
+		Start:    start,
+		Devices:  4,
+		OK:       1,
+		Partial:  1,
+		Failed:   1,
+		Skipped:  1,
+		Critical: 1,
+		Results: []deviceResult{
+			{Mac: "00:00:00:00:00:01", Ip: "10.0.0.1", UUID: "uuid-1", Status: deviceStatusOK, Health: &machineHealth{State: healthOK}},
+			{
+				Mac: "00:00:00:00:00:02", Ip: "10.0.0.2", UUID: "uuid-2", Status: deviceStatusPartial, Errors: []stageError{{Stage: leases.StagePowerState, Error: "timeout"}},
+				Health: &machineHealth{State: healthCritical, Reasons: []string{"power supply 0 health is Critical"}},
+			},
+			{Mac: "00:00:00:00:00:03", Ip: "10.0.0.3", Status: deviceStatusFailed, Errors: []stageError{{Stage: leases.StageConnect, Error: "connection refused"}}},
+			{Mac: "00:00:00:00:00:04", Ip: "10.0.0.4", Status: deviceStatusSkipped},
+		},
+	}
+
+	if diff := cmp.Diff(want, newCycleSummary(start, results)); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
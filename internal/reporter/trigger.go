@@ -0,0 +1,165 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/metal-stack/metal-bmc/internal/leases"
+)
+
+// triggerQueueSize is the number of on demand report cycles which can wait for the running cycle
+const triggerQueueSize = 10
+
+// errTooManyTriggers is returned if the queue of on demand report cycles is full
+var errTooManyTriggers = errors.New("too many report cycles are requested already")
+
+// trigger requests an on demand report cycle which is limited to the given devices, a trigger without devices reports all devices.
+type trigger struct {
+	Macs  []string `json:"macs,omitempty"`
+	Ips   []string `json:"ips,omitempty"`
+	UUIDs []string `json:"uuids,omitempty"`
+}
+
+// all returns true if the trigger is not limited to any device.
+func (t *trigger) all() bool {
+	return t == nil || (len(t.Macs) == 0 && len(t.Ips) == 0 && len(t.UUIDs) == 0)
+}
+
+func (t *trigger) validate() error {
+	for _, ip := range t.Ips {
+		_, err := netip.ParseAddr(ip)
+		if err != nil {
+			return fmt.Errorf("invalid ip %q: %w", ip, err)
+		}
+	}
+	return nil
+}
+
+// TriggerReport requests a report cycle which is limited to the given macs, ips and machine uuids, without any of them
+// all devices are reported. The cycle is run after the running cycle finished.
+func (r *reporter) TriggerReport(macs, ips, uuids []string) error {
+	t := trigger{Macs: macs, Ips: ips, UUIDs: uuids}
+	err := t.validate()
+	if err != nil {
+		return err
+	}
+	return r.enqueueTrigger(t)
+}
+
+func (r *reporter) enqueueTrigger(t trigger) error {
+	select {
+	case r.triggers <- t:
+		r.log.Info("report cycle requested", "macs", t.Macs, "ips", t.Ips, "uuids", t.UUIDs)
+		return nil
+	default:
+		return errTooManyTriggers
+	}
+}
+
+// ServeTrigger requests a report cycle, the devices can be limited by a json body with macs, ips and uuids.
+func (r *reporter) ServeTrigger(w http.ResponseWriter, req *http.Request) {
+	var t trigger
+	err := json.NewDecoder(req.Body).Decode(&t)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return
+	}
+	err = t.validate()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	err = r.enqueueTrigger(t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// selectItems returns the items which match any device of the trigger. The uuid of a device is only known after it was enriched,
+// uuids are therefore resolved with the macs and ips of the previous cycles.
+func (r *reporter) selectItems(items []*leases.ReportItem, t *trigger) []*leases.ReportItem {
+	macs := slices.Clone(t.Macs)
+	var ips []netip.Addr
+	for _, ip := range t.Ips {
+		addr, err := netip.ParseAddr(ip)
+		if err == nil {
+			ips = append(ips, addr)
+		}
+	}
+
+	for _, uuid := range t.UUIDs {
+		resolved := false
+		if report, ok := r.lastReports[uuid]; ok && report.BMCIP != nil {
+			if addr, err := netip.ParseAddr(*report.BMCIP); err == nil {
+				ips = append(ips, addr)
+				resolved = true
+			}
+		}
+		if mac := r.bmcMacs.get(uuid); mac != "" {
+			macs = append(macs, mac)
+			resolved = true
+		}
+		if !resolved {
+			r.log.Warn("unable to find the bmc of the requested machine", "uuid", uuid)
+		}
+	}
+
+	var selected []*leases.ReportItem
+	for _, item := range items {
+		if slices.ContainsFunc(macs, func(mac string) bool {
+			return strings.EqualFold(mac, item.Lease.Mac)
+		}) {
+			selected = append(selected, item)
+			continue
+		}
+		ip, err := netip.ParseAddr(item.Lease.Ip)
+		if err == nil && slices.Contains(ips, ip) {
+			selected = append(selected, item)
+		}
+	}
+	return selected
+}
+
+// bmcMacs contains the bmc mac per machine uuid, it is updated by every successful enrichment and kept across cycles
+type bmcMacs struct {
+	lock sync.RWMutex
+	macs map[string]string
+}
+
+func newBMCMacs() *bmcMacs {
+	return &bmcMacs{
+		macs: make(map[string]string),
+	}
+}
+
+func (b *bmcMacs) get(uuid string) string {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.macs[uuid]
+}
+
+// set remembers the bmc mac of the machine with the given uuid. A partial cycle does not see all candidates of a machine,
+// it only adds machines which are not known yet.
+func (b *bmcMacs) set(uuid, mac string, partial bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.macs[uuid]; ok && partial {
+		return
+	}
+	b.macs[uuid] = mac
+}
+
+// BMCMac returns the mac of the bmc of the machine with the given uuid, an empty string is returned if the machine was never read.
+func (r *reporter) BMCMac(uuid string) string {
+	return r.bmcMacs.get(uuid)
+}
@@ -0,0 +1,132 @@
+package reporter
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/metal-stack/metal-bmc/internal/leases"
+	"github.com/metal-stack/metal-go/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_reporter_selectItems(t *testing.T) {
+	items := []*leases.ReportItem{
+		{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:62", Ip: "10.0.0.1"}},
+		{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:63", Ip: "10.0.0.2"}},
+		{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:64", Ip: "10.0.0.3"}},
+		{Lease: leases.Lease{Mac: "ac:1f:6b:35:ac:65", Ip: "10.0.0.4"}},
+	}
+
+	r := &reporter{
+		log: slog.Default(),
+		lastReports: map[string]models.V1MachineIpmiReport{
+			"c": {BMCIP: new("10.0.0.3")},
+		},
+		bmcMacs: newBMCMacs(),
+	}
+	r.bmcMacs.set("d", "ac:1f:6b:35:ac:65", false)
+
+	tests := []struct {
+		name    string
+		trigger *trigger
+		want    []string
+	}{
+		{
+			name:    "by mac",
+			trigger: &trigger{Macs: []string{"AC:1F:6B:35:AC:62"}},
+			want:    []string{"ac:1f:6b:35:ac:62"},
+		},
+		{
+			name:    "by ip",
+			trigger: &trigger{Ips: []string{"10.0.0.2"}},
+			want:    []string{"ac:1f:6b:35:ac:63"},
+		},
+		{
+			name:    "by uuid of the last reports and the enriched bmcs",
+			trigger: &trigger{UUIDs: []string{"c", "d", "unknown"}},
+			want:    []string{"ac:1f:6b:35:ac:64", "ac:1f:6b:35:ac:65"},
+		},
+		{
+			name:    "nothing matches",
+			trigger: &trigger{Macs: []string{"ac:1f:6b:35:ac:66"}},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, item := range r.selectItems(items, tt.trigger) {
+				got = append(got, item.Lease.Mac)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diff = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_reporter_TriggerReport(t *testing.T) {
+	r := &reporter{
+		log:      slog.Default(),
+		triggers: make(chan trigger, 1),
+	}
+
+	require.EqualError(t, r.TriggerReport(nil, []string{"10.0.0"}, nil), `invalid ip "10.0.0": ParseAddr("10.0.0"): IPv4 address too short`)
+
+	require.NoError(t, r.TriggerReport([]string{"ac:1f:6b:35:ac:62"}, nil, []string{"a"}))
+	require.ErrorIs(t, r.TriggerReport(nil, nil, nil), errTooManyTriggers)
+
+	got := <-r.triggers
+	if diff := cmp.Diff(trigger{Macs: []string{"ac:1f:6b:35:ac:62"}, UUIDs: []string{"a"}}, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
+
+func Test_reporter_ServeTrigger(t *testing.T) {
+	r := &reporter{
+		log:      slog.Default(),
+		triggers: make(chan trigger, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/report/trigger", r.ServeTrigger)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "invalid body", body: `{"macs":`, want: http.StatusBadRequest},
+		{name: "invalid ip", body: `{"ips":["a"]}`, want: http.StatusBadRequest},
+		{name: "all devices", body: ``, want: http.StatusAccepted},
+		{name: "queue is full", body: `{"ips":["10.0.0.1"]}`, want: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/report/trigger", strings.NewReader(tt.body)))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+
+	got := <-r.triggers
+	assert.True(t, got.all())
+}
+
+func Test_bmcMacs(t *testing.T) {
+	b := newBMCMacs()
+	assert.Empty(t, b.get("a"))
+
+	// a partial cycle adds unknown machines but does not replace their bmc
+	b.set("a", "ac:1f:6b:35:ac:62", true)
+	b.set("a", "ac:1f:6b:35:ac:63", true)
+	assert.Equal(t, "ac:1f:6b:35:ac:62", b.get("a"))
+
+	b.set("a", "ac:1f:6b:35:ac:63", false)
+	assert.Equal(t, "ac:1f:6b:35:ac:63", b.get("a"))
+}
@@ -0,0 +1,83 @@
+package reporter
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchFile watches the lease file or the static inventory file for changes and notifies on the returned channel.
+// Changes are debounced, a notification is sent after no further change happened for the configured debounce duration,
+// but at the latest after the configured max wait since the first pending change.
+// The parent directory is watched because dhcpd replaces the lease file on rewrites.
+func (r *reporter) watchFile(done <-chan struct{}, path string) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create file watcher: %w", err)
+	}
+
+	file := filepath.Clean(path)
+	err = watcher.Add(filepath.Dir(file))
+	if err != nil {
+		_ = watcher.Close()
+		return nil, fmt.Errorf("unable to watch directory of %s: %w", file, err)
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer func() {
+			_ = watcher.Close()
+		}()
+
+		debounce := time.NewTimer(r.cfg.LeaseFileDebounce)
+		debounce.Stop()
+
+		// firstChange is the time of the first change which was not notified yet
+		var firstChange time.Time
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != file {
+					continue
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+					continue
+				}
+				r.log.Debug("file changed", "event", event.String())
+				if firstChange.IsZero() {
+					firstChange = time.Now()
+				}
+				wait := r.cfg.LeaseFileDebounce
+				if r.cfg.LeaseFileMaxWait > 0 {
+					wait = max(min(wait, r.cfg.LeaseFileMaxWait-time.Since(firstChange)), 0)
+				}
+				debounce.Reset(wait)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				r.log.Error("error watching file", "file", file, "error", err)
+			case <-debounce.C:
+				firstChange = time.Time{}
+				select {
+				case changes <- struct{}{}:
+				default:
+					// a notification is already pending
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	r.log.Info("watching file for changes", "file", file, "debounce", r.cfg.LeaseFileDebounce.String(), "max-wait", r.cfg.LeaseFileMaxWait.String())
+
+	return changes, nil
+}
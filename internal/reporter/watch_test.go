@@ -0,0 +1,104 @@
+package reporter
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/metal-stack/metal-bmc/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_reporter_watchFile(t *testing.T) {
+	dir := t.TempDir()
+	leaseFile := filepath.Join(dir, "dhcpd.leases")
+	err := os.WriteFile(leaseFile, []byte(leaseFile), 0600)
+	require.NoError(t, err)
+
+	r := &reporter{
+		cfg: &config.Config{
+			LeaseFile:         leaseFile,
+			LeaseFileDebounce: 50 * time.Millisecond,
+		},
+		log: slog.Default(),
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	changes, err := r.watchFile(done, leaseFile)
+	require.NoError(t, err)
+
+	// changes of other files are ignored
+	err = os.WriteFile(filepath.Join(dir, "other"), []byte("other"), 0600)
+	require.NoError(t, err)
+
+	select {
+	case <-changes:
+		t.Fatal("unexpected notification for other file")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// dhcpd writes a new file and renames it
+	tmpFile := filepath.Join(dir, "dhcpd.leases~")
+	for range 3 {
+		err = os.WriteFile(tmpFile, []byte(leaseFile), 0600)
+		require.NoError(t, err)
+		err = os.Rename(tmpFile, leaseFile)
+		require.NoError(t, err)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected notification for lease file change")
+	}
+
+	select {
+	case <-changes:
+		t.Fatal("expected changes to be debounced")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func Test_reporter_watchFile_maxWait(t *testing.T) {
+	dir := t.TempDir()
+	leaseFile := filepath.Join(dir, "dhcpd.leases")
+	err := os.WriteFile(leaseFile, []byte(leaseFile), 0600)
+	require.NoError(t, err)
+
+	r := &reporter{
+		cfg: &config.Config{
+			LeaseFile:         leaseFile,
+			LeaseFileDebounce: 100 * time.Millisecond,
+			LeaseFileMaxWait:  300 * time.Millisecond,
+		},
+		log: slog.Default(),
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	changes, err := r.watchFile(done, leaseFile)
+	require.NoError(t, err)
+
+	// the file changes more often than the debounce duration, the notification is sent after the max wait nevertheless
+	start := time.Now()
+	writes := time.NewTicker(20 * time.Millisecond)
+	defer writes.Stop()
+	for {
+		select {
+		case <-changes:
+			require.Less(t, time.Since(start), time.Second)
+			return
+		case <-writes.C:
+			if time.Since(start) > 2*time.Second {
+				t.Fatal("expected notification after max wait while the file keeps changing")
+			}
+			err = os.WriteFile(leaseFile, []byte(time.Now().String()), 0600)
+			require.NoError(t, err)
+		}
+	}
+}
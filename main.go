@@ -3,10 +3,13 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/metal-stack/metal-bmc/internal/bmc"
+	"github.com/metal-stack/metal-bmc/internal/credentials"
 	"github.com/metal-stack/metal-bmc/pkg/config"
 	metalgo "github.com/metal-stack/metal-go"
 
@@ -14,6 +17,7 @@ import (
 	"github.com/metal-stack/v"
 
 	"github.com/kelseyhightower/envconfig"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
@@ -36,7 +40,14 @@ func main() {
 		level = slog.LevelWarn
 	}
 
-	jsonHandler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	// "metal-bmc report" runs a single report cycle, logs are written to stderr to keep its output parsable
+	reportMode := len(os.Args) > 1 && os.Args[1] == "report"
+	logOutput := os.Stdout
+	if reportMode {
+		logOutput = os.Stderr
+	}
+
+	jsonHandler := slog.NewJSONHandler(logOutput, &slog.HandlerOptions{
 		Level: level,
 	})
 	log := slog.New(jsonHandler)
@@ -50,17 +61,32 @@ func main() {
 		panic(err)
 	}
 
-	// BMC Events via NSQ
-	b := bmc.New(log, &cfg)
+	// Credentials of provisioned BMC users
+	var secrets credentials.Backend
+	if cfg.IpmiUserProvisioning {
+		secrets, err = credentials.NewBackend(cfg.IpmiSecretBackend, cfg.IpmiSecretFile)
+		if err != nil {
+			log.Error("unable to create secret backend", "error", err)
+			panic(err)
+		}
+	}
 
-	err = b.InitConsumer()
-	if err != nil {
-		log.Error("unable to create bmc service", "error", err)
-		panic(err)
+	usage := credentials.NewUsage()
+
+	if reportMode {
+		err := runReport(log, &cfg, client, secrets, usage, os.Args[2:])
+		if err != nil {
+			log.Error("report failed", "error", err)
+			os.Exit(1)
+		}
+		return
 	}
 
+	// BMC Events via NSQ
+	b := bmc.New(log, &cfg, secrets, usage)
+
 	// BMC Console access
-	console, err := bmc.NewConsole(log, client, cfg)
+	console, err := bmc.NewConsole(log, client, cfg, secrets, usage)
 	if err != nil {
 		log.Error("unable to create bmc console", "error", err)
 		panic(err)
@@ -71,13 +97,57 @@ func main() {
 			panic(err)
 		}
 	}()
+	if cfg.ConsoleWebsocketPort != 0 {
+		go func() {
+			err := console.ListenAndServeWebsocket()
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
 
 	// Report IPMI Details
-	r, err := reporter.New(log, &cfg, client)
+	// the event producer is only created when the first event is published
+	var publisher reporter.Publisher
+	if cfg.EventTopic != "" {
+		publisher = b
+	}
+	r, err := reporter.New(log, &cfg, client, secrets, usage, publisher)
 	if err != nil {
 		log.Error("could not start reporter", "error", err)
 		panic(err)
 	}
 
+	// the consumer is started after the reporter is created, report commands are passed to it
+	b.SetReporter(r)
+	err = b.InitConsumer()
+	if err != nil {
+		log.Error("unable to create bmc service", "error", err)
+		panic(err)
+	}
+
+	// Metrics and reporter api
+	if cfg.MetricsServerPort != 0 {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.Handler())
+			mux.HandleFunc("GET /v1/report/summary", r.ServeSummary)
+			if cfg.ReportTriggerAPI {
+				mux.HandleFunc("POST /v1/report/trigger", r.ServeTrigger)
+			}
+			mux.HandleFunc("GET /v1/inventory/{uuid}", r.ServeInventory)
+			server := &http.Server{
+				Addr:              fmt.Sprintf(":%d", cfg.MetricsServerPort),
+				Handler:           mux,
+				ReadHeaderTimeout: time.Minute,
+			}
+			log.Info("starting metrics server", "address", server.Addr)
+			err := server.ListenAndServe()
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
+
 	r.Run()
 }
@@ -12,15 +12,60 @@ type Config struct {
 	PartitionID string `required:"true" desc:"set the partition ID" envconfig:"partition_id"`
 
 	// ipmi details reporting parameters
-	LeaseFile       string        `required:"false" default:"/var/lib/dhcp/dhcpd.leases" desc:"the dhcp lease file to read" split_words:"true"`
-	ReportInterval  time.Duration `required:"false" default:"5m" desc:"the interval for periodical reports" split_words:"true"`
-	MetalAPIURL     *url.URL      `required:"true" desc:"endpoint for the metal-api" envconfig:"metal_api_url"`
-	MetalAPIHMACKey string        `required:"true" desc:"the preshared key for the hmac calculation" envconfig:"metal_api_hmac_key"`
-	IpmiPort        int           `required:"false" default:"623" desc:"the ipmi port" split_words:"true"`
-	IpmiUser        string        `required:"false" default:"ADMIN" desc:"the ipmi user" split_words:"true"`
-	IpmiPassword    string        `required:"false" default:"ADMIN" desc:"the ipmi password" split_words:"true"`
-	IgnoreMacs      []string      `required:"false" desc:"mac addresses to ignore" split_words:"true"`
-	AllowedCidrs    []string      `required:"false" default:"0.0.0.0/0" desc:"filters dhcp leases" split_words:"true"`
+	LeaseSource                  string        `required:"false" default:"isc" desc:"the source of the dhcp leases, either isc, kea-memfile, kea-api or dnsmasq" split_words:"true"`
+	LeaseFile                    string        `required:"false" default:"/var/lib/dhcp/dhcpd.leases" desc:"the dhcp lease file to read" split_words:"true"`
+	StaticInventoryFile          string        `required:"false" default:"" desc:"a yaml or json file with bmcs which have static addresses, they are merged with the dhcp leases" split_words:"true"`
+	KeaAPIURL                    string        `required:"false" default:"" desc:"the url of the kea control agent, required by the kea-api lease source" envconfig:"kea_api_url"`
+	KeaAPIUser                   string        `required:"false" default:"" desc:"the user for the basic authentication at the kea control agent, empty disables the authentication" envconfig:"kea_api_user"`
+	KeaAPIPassword               string        `required:"false" default:"" desc:"the password for the basic authentication at the kea control agent" envconfig:"kea_api_password"`
+	KeaAPITimeout                time.Duration `required:"false" default:"10s" desc:"the timeout of a request to the kea control agent" envconfig:"kea_api_timeout"`
+	KeaAPISubnets                []int64       `required:"false" desc:"the ids of the kea subnets whose leases are read, defaults to all subnets" envconfig:"kea_api_subnets"`
+	KeaAPIPageSize               int           `required:"false" default:"0" desc:"read the leases from the kea control agent in pages of this size, 0 reads all leases at once" envconfig:"kea_api_page_size"`
+	LeaseFileDebounce            time.Duration `required:"false" default:"5s" desc:"the time to wait for further changes of the lease file before reporting" split_words:"true"`
+	LeaseFileMaxWait             time.Duration `required:"false" default:"30s" desc:"the maximum time to wait for the lease file to settle before reporting, 0 waits until no further changes happen" split_words:"true"`
+	ReportInterval               time.Duration `required:"false" default:"5m" desc:"the interval for periodical reports" split_words:"true"`
+	FullReportInterval           time.Duration `required:"false" default:"1h" desc:"the interval for reporting all machines, in between only new or changed reports are sent" split_words:"true"`
+	ReportBatchSize              int           `required:"false" default:"100" desc:"the maximum number of machines sent in one report, 0 sends all machines in one report" split_words:"true"`
+	ReportBatchParallelism       int           `required:"false" default:"4" desc:"the number of report batches sent in parallel" split_words:"true"`
+	ReportBatchRetries           int           `required:"false" default:"4" desc:"the number of retries of a failed report batch" split_words:"true"`
+	ReportBatchRetryDelay        time.Duration `required:"false" default:"5s" desc:"the initial delay between retries of a failed report batch, doubled on every retry" split_words:"true"`
+	ReportBatchMaxRetryDelay     time.Duration `required:"false" default:"1m" desc:"the maximum delay between retries of a failed report batch" split_words:"true"`
+	ReportStateFile              string        `required:"false" default:"" desc:"the file where reports which could not be sent are persisted, empty disables persistence" split_words:"true"`
+	ReportStateMaxAge            time.Duration `required:"false" default:"1h" desc:"persisted reports older than this are discarded after a restart" split_words:"true"`
+	MetalAPIURL                  *url.URL      `required:"true" desc:"endpoint for the metal-api" envconfig:"metal_api_url"`
+	MetalAPIHMACKey              string        `required:"true" desc:"the preshared key for the hmac calculation" envconfig:"metal_api_hmac_key"`
+	IpmiPort                     int           `required:"false" default:"623" desc:"the ipmi port" split_words:"true"`
+	RedfishPort                  int           `required:"false" default:"0" desc:"the port of the redfish api of the bmcs which is used by the reporter, 0 uses the https port" split_words:"true"`
+	IpmiUser                     string        `required:"false" default:"ADMIN" desc:"the ipmi user" split_words:"true"`
+	IpmiPassword                 string        `required:"false" default:"ADMIN" desc:"the ipmi password" split_words:"true"`
+	IpmiCredentialsFile          string        `required:"false" default:"" desc:"a yaml or json file with an ordered list of ipmi credential sets which are tried before the ipmi user and password" split_words:"true"`
+	IpmiUserProvisioning         bool          `required:"false" default:"false" desc:"create a unique administrator user on every new bmc which is used for all further connections" split_words:"true"`
+	IpmiUserPrefix               string        `required:"false" default:"metal" desc:"the prefix of the name of provisioned bmc users, other users of the bmc must not be named like a provisioned user" split_words:"true"`
+	IpmiDisableInitialUser       bool          `required:"false" default:"false" desc:"disable the user of the credential set which was used to provision a bmc user, commands and the console use the provisioned user" split_words:"true"`
+	IpmiSecretBackend            string        `required:"false" default:"file" desc:"the backend where the credentials of provisioned bmc users are stored, currently only file is supported" split_words:"true"`
+	IpmiSecretFile               string        `required:"false" default:"/var/lib/metal-bmc/ipmi-credentials.json" desc:"the file where the credentials of provisioned bmc users are stored by the file backend" split_words:"true"`
+	IpmiPasswordRotationInterval time.Duration `required:"false" default:"0" desc:"the interval after which the password of a provisioned bmc user is rotated, 0 disables the rotation" split_words:"true"`
+	IgnoreMacs                   []string      `required:"false" desc:"mac addresses to ignore" split_words:"true"`
+	AllowedCidrs                 []string      `required:"false" default:"0.0.0.0/0" desc:"filters dhcp leases" split_words:"true"`
+
+	EnrichmentBackoff         time.Duration `required:"false" default:"1m" desc:"the initial backoff for devices whose bmc details could not be read, doubled on every failure" split_words:"true"`
+	EnrichmentMaxBackoff      time.Duration `required:"false" default:"1h" desc:"the maximum backoff for devices whose bmc details could not be read" split_words:"true"`
+	EnrichmentQuarantineAfter int           `required:"false" default:"10" desc:"the number of consecutive failures after which a device is quarantined, 0 disables the quarantine" split_words:"true"`
+
+	CollectLogs        bool          `required:"false" default:"false" desc:"read new entries of the system event log and the redfish logs of every bmc and publish them as events" split_words:"true"`
+	LogClearPercentage int           `required:"false" default:"0" desc:"clear the system event log of a bmc when it is filled by this percentage, 0 disables clearing" split_words:"true"`
+	LogAlertMaxAge     time.Duration `required:"false" default:"24h" desc:"the time a warning or critical log entry degrades the health of a machine unless the log is cleared before, 0 keeps it until the log is cleared" split_words:"true"`
+	LogStateFile       string        `required:"false" default:"" desc:"the file where the position of the last published entry of every log is persisted, empty disables persistence" split_words:"true"`
+	CollectInventory   bool          `required:"false" default:"false" desc:"read the hardware inventory of every bmc and publish an event for every added, removed or replaced component" split_words:"true"`
+
+	AddressFlappingChanges int           `required:"false" default:"3" desc:"the number of bmc address changes of a machine within the flapping window after which it is flagged as flapping, 0 disables the detection" split_words:"true"`
+	AddressFlappingWindow  time.Duration `required:"false" default:"1h" desc:"the window in which bmc address changes of a machine are counted for the flapping detection" split_words:"true"`
+	MissingAfterCycles     int           `required:"false" default:"3" desc:"the number of report cycles without an active lease of a known bmc after which its machine is marked as missing, 0 disables the detection" split_words:"true"`
+
+	// Metrics server parameters, the reporter api is served on the same port
+	MetricsServerPort int  `required:"false" default:"2112" desc:"the port of the metrics server, 0 disables it" split_words:"true"`
+	ReportTriggerAPI  bool `required:"false" default:"false" desc:"serve the unauthenticated endpoint which requests report cycles on the metrics server port" envconfig:"report_trigger_api"`
+	CollectSensors    bool `required:"false" default:"false" desc:"read the temperature, fan, voltage and power sensors of every bmc over redfish and expose them as metrics" split_words:"true"`
 
 	// NSQ connection parameters
 	MQAddress           string        `required:"false" default:"localhost:4150" desc:"set the nsqd server address" envconfig:"mq_address"`
@@ -30,12 +75,15 @@ type Config struct {
 	MQLogLevel          string        `required:"false" default:"warn" desc:"sets the MQ loglevel (debug, info, warn, error)" envconfig:"mq_loglevel"`
 	MachineTopic        string        `required:"false" default:"machine" desc:"set the machine topic name" split_words:"true"`
 	MachineTopicTTL     time.Duration `required:"false" default:"30s" desc:"sets the TTL for MachineTopic" envconfig:"machine_topic_ttl"`
+	EventTopic          string        `required:"false" default:"bmc-event" desc:"set the topic name where events of the reporter are published, empty disables events" split_words:"true"`
 
 	// Console Proxy parameters
-	ConsolePort       int    `required:"false" default:"3333" desc:"defines the port where to listen for incoming console connections from metal-console" envconfig:"console_port"`
-	ConsoleCACertFile string `required:"false" default:"ca.pem" desc:"ca cert file" envconfig:"console_ca_cert_file"`
-	ConsoleCertFile   string `required:"false" default:"cert.pem" desc:"cert file" envconfig:"console_cert_file"`
-	ConsoleKeyFile    string `required:"false" default:"key.pem" desc:"key file" envconfig:"console_key_file"`
+	ConsolePort                    int      `required:"false" default:"3333" desc:"defines the port where to listen for incoming console connections from metal-console" envconfig:"console_port"`
+	ConsoleWebsocketPort           int      `required:"false" default:"0" desc:"defines the port where to serve the console over websocket, 0 disables it" envconfig:"console_websocket_port"`
+	ConsoleWebsocketAllowedOrigins []string `required:"false" desc:"origins which are allowed to open a websocket console, defaults to same origin only" envconfig:"console_websocket_allowed_origins"`
+	ConsoleCACertFile              string   `required:"false" default:"ca.pem" desc:"ca cert file" envconfig:"console_ca_cert_file"`
+	ConsoleCertFile                string   `required:"false" default:"cert.pem" desc:"cert file" envconfig:"console_cert_file"`
+	ConsoleKeyFile                 string   `required:"false" default:"key.pem" desc:"key file" envconfig:"console_key_file"`
 }
 
 func (c *Config) Validate() error {
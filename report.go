@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/metal-stack/metal-bmc/internal/credentials"
+	"github.com/metal-stack/metal-bmc/internal/reporter"
+	"github.com/metal-stack/metal-bmc/pkg/config"
+	metalgo "github.com/metal-stack/metal-go"
+)
+
+const reportUsage = `usage: metal-bmc report [flags]
+
+Runs a single report cycle and prints the reports, nothing is sent to the metal-api unless --submit is given.
+The configuration is read from the environment like for the service.
+
+`
+
+// runReport runs a single report cycle from the command line.
+func runReport(log *slog.Logger, cfg *config.Config, client metalgo.Client, secrets credentials.Backend, usage *credentials.Usage, args []string) error {
+	opts := reporter.OnceOptions{}
+
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(fs.Output(), reportUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opts.Output, "output", reporter.OutputTable, "output format, either table or json")
+	fs.BoolVar(&opts.Submit, "submit", false, "send the reports to the metal-api")
+	fs.BoolVar(&opts.NoEnrich, "no-enrich", false, "only list the leases without connecting to the bmcs")
+	fs.Func("mac", "only report the bmc with this mac address, can be given multiple times or comma separated", func(s string) error {
+		opts.Macs = append(opts.Macs, strings.Split(s, ",")...)
+		return nil
+	})
+	fs.Func("cidr", "only report bmcs in this network, can be given multiple times or comma separated", func(s string) error {
+		for cidr := range strings.SplitSeq(s, ",") {
+			pfx, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return err
+			}
+			opts.Cidrs = append(opts.Cidrs, pfx)
+		}
+		return nil
+	})
+
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	r, err := reporter.New(log, cfg, client, secrets, usage, nil)
+	if err != nil {
+		return err
+	}
+
+	return r.Once(os.Stdout, opts)
+}